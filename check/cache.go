@@ -0,0 +1,389 @@
+package check
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/packages"
+
+	"github.com/abemedia/gocheck/internal/config"
+)
+
+// CacheMode controls the use of the result cache.
+type CacheMode int
+
+const (
+	// CacheOff disables the cache.
+	CacheOff CacheMode = iota
+
+	// CacheRead reuses cached results without storing new ones.
+	CacheRead
+
+	// CacheReadWrite reuses cached results and stores new ones.
+	CacheReadWrite
+)
+
+var cacheModes = []string{"off", "read", "readwrite"}
+
+// String returns the name of the mode.
+func (m CacheMode) String() string {
+	if int(m) < len(cacheModes) {
+		return cacheModes[m]
+	}
+	return fmt.Sprintf("CacheMode(%d)", int(m))
+}
+
+// Set sets the mode from its name, implementing [flag.Value].
+func (m *CacheMode) Set(s string) error {
+	i := slices.Index(cacheModes, s)
+	if i < 0 {
+		return fmt.Errorf("invalid cache mode %q: must be off, read or readwrite", s)
+	}
+	*m = CacheMode(i)
+	return nil
+}
+
+// cacheVersion is changed whenever the format of cache entries changes.
+const cacheVersion = "1"
+
+// DefaultCacheDir returns the default directory of the result cache, gocheck
+// in the user's cache directory.
+func DefaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gocheck"), nil
+}
+
+// CleanCache removes the result cache in dir, or in the default directory if
+// dir is empty.
+func CleanCache(dir string) error {
+	if dir == "" {
+		var err error
+		if dir, err = DefaultCacheDir(); err != nil {
+			return err
+		}
+	}
+	return os.RemoveAll(dir)
+}
+
+// runCached runs the analyzers on the packages whose results aren't cached.
+//
+// Results are cached per package directory, keyed by the files in the
+// directory, the export data of the packages' direct dependencies, the
+// gocheck executable, the analyzers and their flags, and the configuration
+// file. Only the diagnostics are cached, so analyzers with other effects, such
+// as writing a summary or using facts, always run, see [cacheable].
+func runCached(ctx context.Context, patterns []string, opts Options, analyzers []*analysis.Analyzer) ([]Result, error) {
+	dir := opts.CacheDir
+	if dir == "" {
+		var err error
+		if dir, err = DefaultCacheDir(); err != nil {
+			return nil, err
+		}
+	}
+
+	pkgs, err := packages.Load(&packages.Config{
+		Context: ctx,
+		Mode: packages.NeedName | packages.NeedFiles | packages.NeedCompiledGoFiles |
+			packages.NeedImports | packages.NeedDeps | packages.NeedExportFile | packages.NeedModule,
+		Dir:   opts.Dir,
+		Tests: opts.Tests,
+	}, patterns...)
+	if err != nil {
+		return nil, err
+	}
+	if loadErrors(pkgs) != nil || !cacheable(analyzers) {
+		// Let the uncached run report the errors.
+		results, err := analyze(ctx, patterns, opts, analyzers)
+		return flatten(results), err
+	}
+
+	h, err := newCacheHasher(opts, analyzers)
+	if err != nil {
+		return nil, err
+	}
+
+	byDir := make(map[string][]*packages.Package)
+	for _, pkg := range pkgs {
+		byDir[pkg.Dir] = append(byDir[pkg.Dir], pkg)
+	}
+
+	var (
+		results []Result
+		missed  []string
+		keys    = make(map[string]string, len(byDir))
+	)
+	for pkgDir, pkgs := range byDir {
+		key, err := h.key(pkgDir, pkgs)
+		if err != nil {
+			return nil, err
+		}
+		cached, ok := readCache(dir, key)
+		if !ok {
+			keys[pkgDir] = key
+			missed = append(missed, pkgDir)
+			continue
+		}
+		results = append(results, cached...)
+	}
+
+	if len(missed) > 0 {
+		slices.Sort(missed)
+		fresh, err := analyze(ctx, missed, opts, analyzers)
+		if err != nil {
+			return nil, err
+		}
+		for _, pkgDir := range missed {
+			results = append(results, fresh[pkgDir]...)
+			if opts.Cache == CacheReadWrite {
+				if err := writeCache(dir, keys[pkgDir], fresh[pkgDir]); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	return results, nil
+}
+
+// cacheable reports whether the results of the analyzers only depend on the
+// inputs of the cache key and are fully described by their diagnostics. This
+// isn't the case for untested if it only checks changed lines, which depend on
+// the state of the Git repository, or writes a summary of all packages. Facts
+// aren't cached either, as they may depend on parts of the dependencies the
+// key doesn't cover, such as the bodies of their functions.
+func cacheable(analyzers []*analysis.Analyzer) bool {
+	seen := make(map[*analysis.Analyzer]bool)
+	var visit func(a *analysis.Analyzer) bool
+	visit = func(a *analysis.Analyzer) bool {
+		if seen[a] {
+			return true
+		}
+		seen[a] = true
+
+		if len(a.FactTypes) > 0 {
+			return false
+		}
+		if a.Name == "untested" {
+			for _, name := range []string{"since", "diff", "summary"} {
+				if f := a.Flags.Lookup(name); f != nil && f.Value.String() != "" {
+					return false
+				}
+			}
+		}
+		return !slices.ContainsFunc(a.Requires, func(a *analysis.Analyzer) bool { return !visit(a) })
+	}
+
+	return !slices.ContainsFunc(analyzers, func(a *analysis.Analyzer) bool { return !visit(a) })
+}
+
+// executableHash returns the hash of the running executable, which stands in
+// for the version of the analyzers.
+var executableHash = sync.OnceValues(func() ([]byte, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	return hashFile(sha256.New(), exe)
+})
+
+// cacheHasher computes the cache keys of package directories.
+type cacheHasher struct {
+	base []byte                       // hash of the inputs shared by all packages
+	deps map[*packages.Package][]byte // hashes of dependencies
+}
+
+func newCacheHasher(opts Options, analyzers []*analysis.Analyzer) (*cacheHasher, error) {
+	exe, err := executableHash()
+	if err != nil {
+		return nil, err
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "gocheck cache %s\nexe %x\ntests %t\n", cacheVersion, exe, opts.Tests)
+	for _, env := range []string{"GOOS", "GOARCH", "GOFLAGS", "CGO_ENABLED"} {
+		fmt.Fprintf(h, "env %s=%s\n", env, os.Getenv(env))
+	}
+
+	analyzers = slices.Clone(analyzers)
+	slices.SortFunc(analyzers, func(a, b *analysis.Analyzer) int { return cmp.Compare(a.Name, b.Name) })
+	for _, a := range analyzers {
+		fmt.Fprintf(h, "analyzer %s\n", a.Name)
+		a.Flags.VisitAll(func(f *flag.Flag) {
+			fmt.Fprintf(h, "flag %s.%s=%q\n", a.Name, f.Name, f.Value.String())
+		})
+	}
+
+	return &cacheHasher{base: h.Sum(nil), deps: make(map[*packages.Package][]byte)}, nil
+}
+
+// key returns the cache key of the packages in a directory.
+func (c *cacheHasher) key(dir string, pkgs []*packages.Package) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "base %x\ndir %s\n", c.base, dir)
+
+	// Hash all files in the directory, as analyzers may look at files which
+	// aren't part of the packages, such as those excluded by build tags.
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	for _, e := range entries {
+		if !e.Type().IsRegular() {
+			continue
+		}
+		if err := writeFileHash(h, filepath.Join(dir, e.Name())); err != nil {
+			return "", err
+		}
+	}
+
+	slices.SortFunc(pkgs, func(a, b *packages.Package) int { return cmp.Compare(a.ID, b.ID) })
+	for _, pkg := range pkgs {
+		fmt.Fprintf(h, "package %s\n", pkg.ID)
+		for _, name := range pkg.CompiledGoFiles {
+			if err := writeFileHash(h, name); err != nil {
+				return "", err
+			}
+		}
+		for _, path := range slices.Sorted(maps.Keys(pkg.Imports)) {
+			dep, err := c.depHash(pkg.Imports[path])
+			if err != nil {
+				return "", err
+			}
+			fmt.Fprintf(h, "import %s %x\n", path, dep)
+		}
+	}
+
+	if pkgs[0].Module != nil {
+		for _, name := range config.FileNames {
+			err := writeFileHash(h, filepath.Join(pkgs[0].Module.Dir, name))
+			if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return "", err
+			}
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// depHash returns the hash of a dependency's export data, or of its files and
+// dependencies if it has none.
+func (c *cacheHasher) depHash(pkg *packages.Package) ([]byte, error) {
+	if sum, ok := c.deps[pkg]; ok {
+		return sum, nil
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "package %s\n", pkg.ID)
+	if pkg.ExportFile != "" {
+		if err := writeFileHash(h, pkg.ExportFile); err != nil {
+			return nil, err
+		}
+	} else {
+		for _, name := range pkg.CompiledGoFiles {
+			if err := writeFileHash(h, name); err != nil {
+				return nil, err
+			}
+		}
+		for _, path := range slices.Sorted(maps.Keys(pkg.Imports)) {
+			dep, err := c.depHash(pkg.Imports[path])
+			if err != nil {
+				return nil, err
+			}
+			fmt.Fprintf(h, "import %s %x\n", path, dep)
+		}
+	}
+
+	c.deps[pkg] = h.Sum(nil)
+	return c.deps[pkg], nil
+}
+
+// writeFileHash writes the name and content hash of a file to h.
+func writeFileHash(h io.Writer, name string) error {
+	sum, err := hashFile(sha256.New(), name)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(h, "file %s %x\n", name, sum)
+	return err
+}
+
+func hashFile(h hash.Hash, name string) ([]byte, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// cachePath returns the path of the cache entry for the key.
+func cachePath(dir, key string) string {
+	return filepath.Join(dir, key[:2], key+".json")
+}
+
+// readCache returns the cached results for the key, if any.
+func readCache(dir, key string) ([]Result, bool) {
+	b, err := os.ReadFile(cachePath(dir, key))
+	if err != nil {
+		return nil, false
+	}
+	var results []Result
+	if err := json.Unmarshal(b, &results); err != nil {
+		return nil, false
+	}
+	return results, true
+}
+
+// writeCache stores the results for the key. The entry is written to a
+// temporary file first, so concurrent runs never read partial entries.
+func writeCache(dir, key string, results []Result) error {
+	if results == nil {
+		results = []Result{}
+	}
+	b, err := json.Marshal(results)
+	if err != nil {
+		return err
+	}
+
+	name := cachePath(dir, key)
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(name), key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), name)
+}
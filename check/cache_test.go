@@ -0,0 +1,230 @@
+package check_test
+
+import (
+	"context"
+	"flag"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/abemedia/gocheck/check"
+)
+
+// writeModule writes a module with two untested functions to a temporary
+// directory.
+func writeModule(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod": "module example.com/a\n\ngo 1.22\n",
+		"a.go":   "package a\n\nfunc A() {}\n",
+		"b.go":   "package a\n\nfunc B() {}\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// cacheEntries returns the paths of the entries in the cache directory.
+func cacheEntries(t *testing.T, dir string) []string {
+	t.Helper()
+	var entries []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			entries = append(entries, path)
+		}
+		return err
+	})
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	return entries
+}
+
+func messages(results []check.Result) string {
+	var msgs []string
+	for _, r := range results {
+		msgs = append(msgs, r.Message)
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func TestRunCache(t *testing.T) {
+	dir := writeModule(t)
+	opts := check.Options{Dir: dir, Tests: true, Cache: check.CacheReadWrite, CacheDir: t.TempDir()}
+
+	results, err := check.Run(context.Background(), []string{"./..."}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "exported function \"A\" has no test\nexported function \"B\" has no test"
+	if got := messages(results); got != want {
+		t.Fatalf("Run() = %q, want %q", got, want)
+	}
+
+	entries := cacheEntries(t, opts.CacheDir)
+	if len(entries) != 1 {
+		t.Fatalf("got cache entries %q, want one", entries)
+	}
+
+	// Tamper with the entry to tell cached results from fresh ones.
+	b, err := os.ReadFile(entries[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(entries[0], []byte(strings.ReplaceAll(string(b), "no test", "cached")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err = check.Run(context.Background(), []string{"./..."}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := messages(results), strings.ReplaceAll(want, "no test", "cached"); got != want {
+		t.Errorf("Run() with cached results = %q, want %q", got, want)
+	}
+
+	t.Run("Off", func(t *testing.T) {
+		opts := opts
+		opts.Cache = check.CacheOff
+		results, err := check.Run(context.Background(), []string{"./..."}, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := messages(results); got != want {
+			t.Errorf("Run() = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("Flags", func(t *testing.T) {
+		opts := opts
+		opts.Analyzers = check.Analyzers()
+		if err := opts.Analyzers[1].Flags.Set("exclude", "b.go"); err != nil {
+			t.Fatal(err)
+		}
+		results, err := check.Run(context.Background(), []string{"./..."}, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := messages(results), `exported function "A" has no test`; got != want {
+			t.Errorf("Run() = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("Summary", func(t *testing.T) {
+		opts := opts
+		opts.Analyzers = check.Analyzers()
+		summary := filepath.Join(t.TempDir(), "summary.json")
+		if err := opts.Analyzers[1].Flags.Set("summary", summary); err != nil {
+			t.Fatal(err)
+		}
+
+		// The summary is written on every run, rather than only the first.
+		for range 2 {
+			if err := os.RemoveAll(summary); err != nil {
+				t.Fatal(err)
+			}
+			results, err := check.Run(context.Background(), []string{"./..."}, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := messages(results); got != want {
+				t.Errorf("Run() = %q, want %q", got, want)
+			}
+			b, err := os.ReadFile(summary)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(b), `"package": "example.com/a"`) {
+				t.Errorf("summary = %s, want example.com/a", b)
+			}
+		}
+	})
+
+	t.Run("Facts", func(t *testing.T) {
+		opts := opts
+		opts.CacheDir = t.TempDir()
+		opts.Analyzers = []*analysis.Analyzer{{
+			Name:      "facts",
+			Doc:       "export a fact for each package",
+			FactTypes: []analysis.Fact{new(fact)},
+			Run: func(pass *analysis.Pass) (any, error) {
+				pass.ExportPackageFact(new(fact))
+				return nil, nil
+			},
+		}}
+		if _, err := check.Run(context.Background(), []string{"./..."}, opts); err != nil {
+			t.Fatal(err)
+		}
+		if entries := cacheEntries(t, opts.CacheDir); len(entries) != 0 {
+			t.Errorf("got cache entries %q, want none", entries)
+		}
+	})
+
+	t.Run("Files", func(t *testing.T) {
+		err := os.WriteFile(filepath.Join(dir, "a_test.go"), []byte("package a\n\nimport \"testing\"\n\nfunc TestA(t *testing.T) { A() }\n"), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		results, err := check.Run(context.Background(), []string{"./..."}, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := messages(results), `exported function "B" has no test`; got != want {
+			t.Errorf("Run() = %q, want %q", got, want)
+		}
+	})
+}
+
+type fact struct{}
+
+func (*fact) AFact() {}
+
+func TestRunCacheRead(t *testing.T) {
+	opts := check.Options{Dir: writeModule(t), Cache: check.CacheRead, CacheDir: t.TempDir()}
+	if _, err := check.Run(context.Background(), []string{"./..."}, opts); err != nil {
+		t.Fatal(err)
+	}
+	if entries := cacheEntries(t, opts.CacheDir); len(entries) != 0 {
+		t.Errorf("got cache entries %q, want none", entries)
+	}
+}
+
+func TestCleanCache(t *testing.T) {
+	opts := check.Options{Dir: writeModule(t), Cache: check.CacheReadWrite, CacheDir: filepath.Join(t.TempDir(), "gocheck")}
+	if _, err := check.Run(context.Background(), []string{"./..."}, opts); err != nil {
+		t.Fatal(err)
+	}
+	if err := check.CleanCache(opts.CacheDir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(opts.CacheDir); !os.IsNotExist(err) {
+		t.Errorf("cache directory still exists: %v", err)
+	}
+}
+
+func TestCacheMode(t *testing.T) {
+	var _ flag.Value = new(check.CacheMode)
+
+	for _, s := range []string{"off", "read", "readwrite"} {
+		var m check.CacheMode
+		if err := m.Set(s); err != nil {
+			t.Fatal(err)
+		}
+		if got := m.String(); got != s {
+			t.Errorf("String() = %q, want %q", got, s)
+		}
+	}
+
+	var m check.CacheMode
+	if err := m.Set("on"); err == nil {
+		t.Error("expected error for invalid mode")
+	}
+}
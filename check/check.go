@@ -0,0 +1,274 @@
+// Package check runs the gocheck analyzers programmatically, for embedding
+// gocheck in other tools without running the gocheck command.
+package check
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"go/token"
+	"maps"
+	"slices"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/checker"
+	"golang.org/x/tools/go/packages"
+
+	"github.com/abemedia/gocheck/fieldorder"
+	"github.com/abemedia/gocheck/unreachable"
+	"github.com/abemedia/gocheck/untested"
+)
+
+// Analyzers returns the gocheck analyzers.
+//
+// The analyzers keep their flags in package-level variables, so creating them
+// resets their flags to the defaults. Set flags through the Flags field of the
+// analyzers returned.
+func Analyzers() []*analysis.Analyzer {
+	return []*analysis.Analyzer{fieldorder.NewAnalyzer(), untested.NewAnalyzer(), unreachable.NewAnalyzer()}
+}
+
+// Options configure a run of the analyzers.
+type Options struct {
+	// Analyzers are the analyzers to run, or all of Analyzers if nil.
+	Analyzers []*analysis.Analyzer
+
+	// Dir is the directory the patterns are relative to, or the current
+	// directory if empty.
+	Dir string
+
+	// Tests analyzes the test files of the packages too.
+	Tests bool
+
+	// Cache controls the use of the on-disk result cache, which is off by
+	// default. Cached results are reused for packages whose files,
+	// dependencies, analyzers and flags are unchanged. Only diagnostics are
+	// cached, so runs of analyzers using facts bypass the cache.
+	Cache CacheMode
+
+	// CacheDir is the directory of the result cache, or DefaultCacheDir if
+	// empty.
+	CacheDir string
+}
+
+// Result is a diagnostic reported by an analyzer.
+type Result struct {
+	Analyzer string
+	Position token.Position
+	End      token.Position // invalid if the diagnostic has no range
+	Category string
+	Message  string
+	URL      string
+	Related  []Related
+	Fixes    []Fix
+}
+
+// Related is a location related to a diagnostic.
+type Related struct {
+	Position token.Position
+	End      token.Position
+	Message  string
+}
+
+// Fix is a suggested fix for a diagnostic.
+type Fix struct {
+	Message string
+	Edits   []Edit
+}
+
+// Edit replaces the text from Position up to End with NewText.
+type Edit struct {
+	Position token.Position
+	End      token.Position
+	NewText  string
+}
+
+// Run loads the packages matching the patterns and runs the analyzers on them.
+// It returns the diagnostics sorted by position, without the duplicates
+// reported for both a package and its test variant.
+func Run(ctx context.Context, patterns []string, opts Options) ([]Result, error) {
+	analyzers := opts.Analyzers
+	if analyzers == nil {
+		analyzers = Analyzers()
+	}
+
+	var (
+		results []Result
+		err     error
+	)
+	if opts.Cache == CacheOff {
+		var byDir map[string][]Result
+		byDir, err = analyze(ctx, patterns, opts, analyzers)
+		results = flatten(byDir)
+	} else {
+		results, err = runCached(ctx, patterns, opts, analyzers)
+	}
+	if err != nil {
+		// Loading fails in many ways once the context is canceled.
+		return nil, cmp.Or(ctx.Err(), err)
+	}
+
+	return sortResults(results), nil
+}
+
+// LoadMode is the least information packages must be loaded with to be
+// analyzed by [Analyze].
+const LoadMode = packages.LoadSyntax
+
+// Analyze runs the analyzers, or all of Analyzers if nil, on packages already
+// loaded with at least [LoadMode], such as packages kept in memory between
+// runs. The packages should come from a single load, as analyzers such as
+// untested tell runs apart by their file set. Like [Run], it returns the
+// diagnostics sorted by position, without duplicates, but doesn't use the
+// result cache.
+func Analyze(ctx context.Context, pkgs []*packages.Package, analyzers []*analysis.Analyzer) ([]Result, error) {
+	if analyzers == nil {
+		analyzers = Analyzers()
+	}
+	if err := loadErrors(pkgs); err != nil {
+		return nil, err
+	}
+
+	results, err := analyzePackages(ctx, pkgs, analyzers)
+	if err != nil {
+		return nil, cmp.Or(ctx.Err(), err)
+	}
+
+	return sortResults(flatten(results)), nil
+}
+
+// sortResults sorts the results by position, removing the duplicates reported
+// for both a package and its test variant.
+func sortResults(results []Result) []Result {
+	type key struct {
+		analyzer string
+		pos      token.Position
+		message  string
+	}
+	seen := make(map[key]bool)
+	results = slices.DeleteFunc(results, func(r Result) bool {
+		k := key{r.Analyzer, r.Position, r.Message}
+		if seen[k] {
+			return true
+		}
+		seen[k] = true
+		return false
+	})
+
+	slices.SortFunc(results, func(a, b Result) int {
+		return cmp.Or(
+			cmp.Compare(a.Position.Filename, b.Position.Filename),
+			cmp.Compare(a.Position.Offset, b.Position.Offset),
+			cmp.Compare(a.Analyzer, b.Analyzer),
+			cmp.Compare(a.Message, b.Message),
+		)
+	})
+
+	return results
+}
+
+// analyze loads the packages matching the patterns and runs the analyzers on
+// them, returning the diagnostics by package directory.
+func analyze(ctx context.Context, patterns []string, opts Options, analyzers []*analysis.Analyzer) (map[string][]Result, error) {
+	pkgs, err := packages.Load(&packages.Config{
+		Context: ctx,
+		Mode:    LoadMode,
+		Dir:     opts.Dir,
+		Tests:   opts.Tests,
+	}, patterns...)
+	if err != nil {
+		return nil, err
+	}
+	if err := loadErrors(pkgs); err != nil {
+		return nil, err
+	}
+
+	return analyzePackages(ctx, pkgs, analyzers)
+}
+
+// analyzePackages runs the analyzers on the loaded packages, returning the
+// diagnostics by package directory.
+func analyzePackages(ctx context.Context, pkgs []*packages.Package, analyzers []*analysis.Analyzer) (map[string][]Result, error) {
+	graph, err := checker.Analyze(analyzers, pkgs, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var (
+		results = make(map[string][]Result)
+		errs    []error
+	)
+	for _, act := range graph.Roots {
+		if act.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %s: %w", act.Analyzer.Name, act.Package.PkgPath, act.Err))
+			continue
+		}
+
+		for _, d := range act.Diagnostics {
+			results[act.Package.Dir] = append(results[act.Package.Dir], newResult(act.Package.Fset, act.Analyzer, d))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	return results, nil
+}
+
+// flatten returns the results of all package directories.
+func flatten(byDir map[string][]Result) []Result {
+	var results []Result
+	for _, dir := range slices.Sorted(maps.Keys(byDir)) {
+		results = append(results, byDir[dir]...)
+	}
+	return results
+}
+
+// loadErrors returns the errors loading the packages and their dependencies.
+func loadErrors(pkgs []*packages.Package) error {
+	var errs []error
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		for _, err := range pkg.Errors {
+			errs = append(errs, err)
+		}
+	})
+	return errors.Join(errs...)
+}
+
+// newResult converts a diagnostic.
+func newResult(fset *token.FileSet, a *analysis.Analyzer, d analysis.Diagnostic) Result {
+	result := Result{
+		Analyzer: a.Name,
+		Position: fset.Position(d.Pos),
+		End:      fset.Position(d.End),
+		Category: d.Category,
+		Message:  d.Message,
+		URL:      d.URL,
+	}
+
+	for _, related := range d.Related {
+		result.Related = append(result.Related, Related{
+			Position: fset.Position(related.Pos),
+			End:      fset.Position(related.End),
+			Message:  related.Message,
+		})
+	}
+
+	for _, fix := range d.SuggestedFixes {
+		edits := make([]Edit, len(fix.TextEdits))
+		for i, edit := range fix.TextEdits {
+			end := edit.End
+			if !end.IsValid() {
+				end = edit.Pos
+			}
+			edits[i] = Edit{Position: fset.Position(edit.Pos), End: fset.Position(end), NewText: string(edit.NewText)}
+		}
+		result.Fixes = append(result.Fixes, Fix{Message: fix.Message, Edits: edits})
+	}
+
+	return result
+}
@@ -0,0 +1,101 @@
+package check_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/packages"
+
+	"github.com/abemedia/gocheck/check"
+	"github.com/abemedia/gocheck/fieldorder"
+)
+
+func TestRun(t *testing.T) {
+	results, err := check.Run(context.Background(), []string{"./a"}, check.Options{Dir: "testdata", Tests: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, r := range results {
+		got = append(got, fmt.Sprintf("%s:%d:%d: %s: %s", filepath.Base(r.Position.Filename), r.Position.Line, r.Position.Column, r.Analyzer, r.Message))
+	}
+	want := []string{
+		`a.go:12:1: untested: exported function "Adult" has no test`,
+		`a.go:14:15: fieldorder: struct literal fields are out of order`,
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("Run() = %q, want %q", got, want)
+	}
+
+	fix := results[1].Fixes
+	if len(fix) != 1 || len(fix[0].Edits) != 2 {
+		t.Fatalf("Fixes = %+v, want one fix with two edits", fix)
+	}
+	if edit := fix[0].Edits[0]; edit.NewText != `Name: "John"` || edit.Position.Column != 16 || edit.End.Column != 23 {
+		t.Errorf("Edits[0] = %+v", edit)
+	}
+}
+
+func TestRunAnalyzers(t *testing.T) {
+	opts := check.Options{Analyzers: []*analysis.Analyzer{fieldorder.NewAnalyzer()}, Dir: "testdata"}
+	results, err := check.Run(context.Background(), []string{"./a"}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 1 || results[0].Analyzer != "fieldorder" {
+		t.Errorf("Run() = %+v, want a fieldorder result", results)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := check.Run(ctx, []string{"./a"}, check.Options{Dir: "testdata"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Run() with canceled context = %v, want %v", err, context.Canceled)
+	}
+	if _, err := check.Run(context.Background(), []string{"./missing"}, check.Options{Dir: "testdata"}); err == nil {
+		t.Error("Run() expected error for missing package")
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	pkgs, err := packages.Load(&packages.Config{Mode: check.LoadMode, Dir: "testdata", Tests: true}, "./a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := check.Analyze(context.Background(), pkgs, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := check.Run(context.Background(), []string{"./a"}, check.Options{Dir: "testdata", Tests: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Analyze() = %+v, want %+v", got, want)
+	}
+}
+
+func TestAnalyzers(t *testing.T) {
+	var names []string
+	for _, a := range check.Analyzers() {
+		if err := analysis.Validate([]*analysis.Analyzer{a}); err != nil {
+			t.Error(err)
+		}
+		names = append(names, a.Name)
+	}
+
+	if want := []string{"fieldorder", "untested", "unreachable"}; !slices.Equal(names, want) {
+		t.Errorf("Analyzers() = %v, want %v", names, want)
+	}
+}
@@ -0,0 +1,14 @@
+package a
+
+type Person struct {
+	Name string
+	Age  int
+}
+
+// Greeting is tested.
+func Greeting() string { return "hello" }
+
+// Adult has no test.
+func Adult(p Person) bool { return p.Age >= 18 }
+
+var _ = Person{Age: 30, Name: "John"}
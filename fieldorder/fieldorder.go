@@ -17,15 +17,22 @@ import (
 	"github.com/abemedia/gocheck/internal/skip"
 )
 
+var excludeFlag skip.Exclude
+
 // NewAnalyzer creates a new analysis.Analyzer that checks struct literal
 // fields are in the same order as the type declaration.
 func NewAnalyzer() *analysis.Analyzer {
-	return &analysis.Analyzer{
+	analyzer := &analysis.Analyzer{
 		Name:     "fieldorder",
 		Doc:      "check that struct literal fields are in the same order as the type declaration",
 		Run:      run,
 		Requires: []*analysis.Analyzer{inspect.Analyzer},
 	}
+
+	excludeFlag = skip.Exclude{}
+	analyzer.Flags.Var(&excludeFlag, "exclude", "comma-separated list of file globs, re:, pkg: or marker: patterns to skip")
+
+	return analyzer
 }
 
 //nolint:funlen,gocognit
@@ -33,7 +40,11 @@ func run(pass *analysis.Pass) (any, error) {
 	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
 	nodeFilter := []ast.Node{(*ast.CompositeLit)(nil)}
 
-	shouldSkip := skip.NewFileStrategy(pass, ast.IsGenerated)
+	shouldSkip := skip.Any(
+		skip.NewFileStrategy(pass, ast.IsGenerated),
+		skip.NewExcludeStrategy(pass, &excludeFlag),
+	)
+	directives := skip.NewDirectives(pass)
 
 	inspect.Preorder(nodeFilter, func(n ast.Node) {
 		cl := n.(*ast.CompositeLit)
@@ -113,7 +124,10 @@ func run(pass *analysis.Pass) (any, error) {
 			}
 		}
 
-		if len(edits) > 0 {
+		if len(edits) == 0 {
+			return
+		}
+		if _, ok := directives.Suppressed(cl); !ok {
 			pass.Report(analysis.Diagnostic{
 				Pos:      cl.Lbrace,
 				End:      cl.Rbrace + 1,
@@ -126,5 +140,7 @@ func run(pass *analysis.Pass) (any, error) {
 		}
 	})
 
+	directives.Report(skip.Not(shouldSkip))
+
 	return nil, nil
 }
@@ -12,3 +12,11 @@ func TestFieldOrder(t *testing.T) {
 	testdata := analysistest.TestData()
 	analysistest.RunWithSuggestedFixes(t, testdata, fieldorder.NewAnalyzer(), "fieldorder")
 }
+
+func TestFieldOrderWithExclude(t *testing.T) {
+	analyzer := fieldorder.NewAnalyzer()
+	analyzer.Flags.Set("exclude", "*_mock.go")
+
+	testdata := analysistest.TestData()
+	analysistest.Run(t, testdata, analyzer, "exclude")
+}
@@ -0,0 +1,11 @@
+package exclude
+
+type Person struct {
+	Name string
+	Age  int
+}
+
+var _ = Person{ // want "struct literal fields are out of order"
+	Age:  30,
+	Name: "John",
+}
@@ -0,0 +1,7 @@
+package exclude
+
+// This file matches the exclusion glob and is skipped.
+var _ = Person{
+	Age:  30,
+	Name: "John",
+}
@@ -0,0 +1,41 @@
+package fieldorder
+
+func _() {
+	type Person struct {
+		Name string
+		Age  int
+	}
+
+	// suppressed by a directive on its own line
+	//nolint:fieldorder
+	_ = Person{
+		Age:  30,
+		Name: "John",
+	}
+
+	_ = Person{Age: 30, Name: "John"} //gocheck:ignore fieldorder order matches the spec
+
+	// suppressed for other analyzers only
+	_ = Person{Age: 30, Name: "John"} //nolint:untested // want "struct literal fields are out of order"
+
+	// want +1 "//gocheck:ignore directive requires a reason"
+	_ = Person{Age: 30, Name: "John"} //gocheck:ignore fieldorder
+
+	// want +1 "//gocheck:ignore directive does not suppress anything"
+	_ = Person{Name: "John", Age: 30} //gocheck:ignore fieldorder ordered already
+
+	// directives naming several analyzers may be used by the others
+	_ = Person{Name: "John", Age: 30} //gocheck:ignore fieldorder,untested ordered already
+}
+
+// suppressed for the whole function
+//
+//nolint:all
+func _() {
+	type Person struct {
+		Name string
+		Age  int
+	}
+
+	_ = Person{Age: 30, Name: "John"}
+}
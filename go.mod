@@ -2,9 +2,15 @@ module github.com/abemedia/gocheck
 
 go 1.25.0
 
-require golang.org/x/tools v0.43.0
+require (
+	github.com/BurntSushi/toml v1.6.0
+	github.com/santhosh-tekuri/jsonschema/v6 v6.0.2
+	go.yaml.in/yaml/v3 v3.0.4
+	golang.org/x/tools v0.43.0
+)
 
 require (
 	golang.org/x/mod v0.34.0 // indirect
 	golang.org/x/sync v0.20.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
 )
@@ -0,0 +1,340 @@
+// Package config loads the project-wide gocheck configuration and applies it
+// to the analyzers' flags.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+	"go.yaml.in/yaml/v3"
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/abemedia/gocheck/internal/skip"
+)
+
+// FileNames are the names of the configuration files looked up in the module
+// root, in order of precedence.
+var FileNames = []string{".gocheck.yaml", ".gocheck.yml", "gocheck.toml"}
+
+// Config is the project-wide configuration.
+type Config struct {
+	// Root is the directory containing the configuration file, which paths of
+	// overrides are relative to.
+	Root string `yaml:"-" toml:"-"`
+
+	// Exclude lists exclusion patterns applied to every analyzer.
+	Exclude []string `yaml:"exclude" toml:"exclude"`
+
+	// Analyzers maps analyzer names to their options.
+	Analyzers map[string]Options `yaml:"analyzers" toml:"analyzers"`
+
+	// Overrides set analyzer options for the packages within a directory.
+	Overrides []Override `yaml:"overrides" toml:"overrides"`
+}
+
+// Options maps the flags of an analyzer, without the analyzer's prefix, to
+// their values. The "enabled" option enables or disables the analyzer. Lists
+// are joined with commas.
+type Options map[string]any
+
+// Override sets analyzer options for the packages within a directory.
+type Override struct {
+	// Path is the directory, relative to the configuration file.
+	Path string `yaml:"path" toml:"path"`
+
+	// Analyzers maps analyzer names to their options.
+	Analyzers map[string]Options `yaml:"analyzers" toml:"analyzers"`
+}
+
+// Find looks up the configuration file in the root of the module containing
+// dir. It returns nil if there is none.
+func Find(dir string) (*Config, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+
+	for _, name := range FileNames {
+		cfg, err := Load(filepath.Join(dir, name))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		return cfg, err
+	}
+
+	return nil, nil
+}
+
+// Load reads a YAML or TOML configuration file, depending on its extension.
+func Load(path string) (*Config, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &Config{Root: filepath.Dir(path)}
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(b, cfg)
+	case ".toml":
+		err = toml.Unmarshal(b, cfg)
+	default:
+		return nil, fmt.Errorf("%s: unsupported configuration format", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return cfg, nil
+}
+
+// Apply sets the flags of the analyzers to the configured options and returns
+// the enabled analyzers. Flags set on the command line, given by their names
+// such as "untested.min-ratio", take precedence: they are parsed afterwards and
+// overrides leave them unchanged.
+//
+// The runs of analyzers with overrides are wrapped to set the options of the
+// directory of each package. As analyzers keep their options in package-level
+// variables, these analyzers analyze one package at a time.
+func (c *Config) Apply(analyzers []*analysis.Analyzer, set map[string]bool) ([]*analysis.Analyzer, error) {
+	byName := make(map[string]*analysis.Analyzer, len(analyzers))
+	for _, a := range analyzers {
+		byName[a.Name] = a
+	}
+
+	for name := range c.Analyzers {
+		if byName[name] == nil {
+			return nil, fmt.Errorf("unknown analyzer %q", name)
+		}
+	}
+
+	overrides := make(map[string][]override)
+	for _, o := range c.Overrides {
+		if o.Path == "" || filepath.IsAbs(o.Path) {
+			return nil, fmt.Errorf("override path %q must be relative to %s", o.Path, c.Root)
+		}
+		dir := filepath.Join(c.Root, filepath.FromSlash(o.Path))
+		for name, opts := range o.Analyzers {
+			a := byName[name]
+			if a == nil {
+				return nil, fmt.Errorf("unknown analyzer %q", name)
+			}
+			if _, ok := opts["enabled"]; ok {
+				return nil, fmt.Errorf("%s: analyzers can't be enabled or disabled per directory, use exclude instead", o.Path)
+			}
+			values, err := flagValues(a, opts)
+			if err != nil {
+				return nil, err
+			}
+			for flag := range values {
+				if set[name+"."+flag] {
+					delete(values, flag)
+				}
+			}
+			overrides[name] = append(overrides[name], override{dir: dir, values: values})
+		}
+	}
+
+	var enabled []*analysis.Analyzer
+	for _, a := range analyzers {
+		opts := c.Analyzers[a.Name]
+
+		if v, ok := opts["enabled"]; ok {
+			on, ok := v.(bool)
+			if !ok {
+				return nil, fmt.Errorf("%s: enabled must be a boolean", a.Name)
+			}
+			if !on {
+				continue
+			}
+		}
+
+		values, err := flagValues(a, opts)
+		if err != nil {
+			return nil, err
+		}
+		if len(c.Exclude) > 0 && a.Flags.Lookup("exclude") != nil {
+			values["exclude"] = strings.Join(slices.Concat(c.Exclude, splitList(values["exclude"])), ",")
+		}
+		for name, value := range values {
+			if err := setFlag(a.Flags.Lookup(name), value); err != nil {
+				return nil, fmt.Errorf("%s: %s: %w", a.Name, name, err)
+			}
+		}
+
+		if o := overrides[a.Name]; len(o) > 0 {
+			a.Run = withOverrides(a.Run, &a.Flags, o)
+		}
+
+		enabled = append(enabled, a)
+	}
+
+	return enabled, nil
+}
+
+// Args returns the command-line arguments prefixed with flags disabling the
+// analyzers disabled in the configuration. This keeps the analyzers registered,
+// so their flags, which are parsed afterwards, can still enable them.
+func (c *Config) Args(args []string) []string {
+	var flags []string
+	for name, opts := range c.Analyzers {
+		if on, ok := opts["enabled"].(bool); ok && !on {
+			flags = append(flags, "-"+name+"=false")
+		}
+	}
+	slices.Sort(flags)
+
+	return append(flags, args...)
+}
+
+// override holds the flag values of an analyzer for the packages in a directory.
+type override struct {
+	dir    string
+	values map[string]string
+}
+
+// withOverrides wraps the run function of an analyzer to set its flags to the
+// values of the overrides matching the package's directory while it runs.
+func withOverrides(run func(*analysis.Pass) (any, error), flags *flag.FlagSet, overrides []override) func(*analysis.Pass) (any, error) {
+	var (
+		mu   sync.Mutex
+		once sync.Once
+		base map[string]string
+	)
+
+	return func(pass *analysis.Pass) (any, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		// Snapshot the values on the first run, after the command line is parsed.
+		once.Do(func() {
+			base = make(map[string]string)
+			for _, o := range overrides {
+				for name := range o.values {
+					base[name] = flags.Lookup(name).Value.String()
+				}
+			}
+		})
+
+		dir := packageDir(pass)
+		values := make(map[string]string)
+		for _, o := range overrides {
+			if dir == o.dir || strings.HasPrefix(dir, o.dir+string(filepath.Separator)) {
+				for name, value := range o.values {
+					// Exclusions of overrides add to the configured ones.
+					if name == "exclude" && base[name] != "" {
+						value = base[name] + "," + value
+					}
+					values[name] = value
+				}
+			}
+		}
+		if len(values) == 0 {
+			return run(pass)
+		}
+
+		for name, value := range values {
+			if err := setFlag(flags.Lookup(name), value); err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+		}
+		defer func() {
+			for name := range values {
+				_ = setFlag(flags.Lookup(name), base[name])
+			}
+		}()
+
+		return run(pass)
+	}
+}
+
+// packageDir returns the directory of the package being analyzed, or an empty
+// string if it has no files.
+func packageDir(pass *analysis.Pass) string {
+	if len(pass.Files) == 0 {
+		return ""
+	}
+	return filepath.Dir(pass.Fset.Position(pass.Files[0].Package).Filename)
+}
+
+// flagValues converts the options of an analyzer to flag values.
+func flagValues(a *analysis.Analyzer, opts Options) (map[string]string, error) {
+	values := make(map[string]string, len(opts))
+	for name, v := range opts {
+		if name == "enabled" {
+			continue
+		}
+		if a.Flags.Lookup(name) == nil {
+			return nil, fmt.Errorf("analyzer %q has no option %q", a.Name, name)
+		}
+		value, err := formatValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", a.Name, name, err)
+		}
+		values[name] = value
+	}
+	return values, nil
+}
+
+// formatValue formats a decoded option as a flag value.
+func formatValue(v any) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int, int64, uint64, float64:
+		return fmt.Sprint(v), nil
+	case []any:
+		elems := make([]string, len(v))
+		for i, elem := range v {
+			s, err := formatValue(elem)
+			if err != nil {
+				return "", err
+			}
+			elems[i] = s
+		}
+		return strings.Join(elems, ","), nil
+	default:
+		return "", fmt.Errorf("unsupported value %v", v)
+	}
+}
+
+// setFlag sets a flag to the given value. Exclusion lists accumulate the
+// values they are set to, so they are cleared first.
+func setFlag(f *flag.Flag, value string) error {
+	if e, ok := f.Value.(*skip.Exclude); ok {
+		*e = skip.Exclude{}
+	}
+	return f.Value.Set(value)
+}
+
+// splitList splits a comma-separated list, ignoring empty elements.
+func splitList(s string) []string {
+	return slices.DeleteFunc(strings.Split(s, ","), func(s string) bool { return s == "" })
+}
@@ -0,0 +1,291 @@
+package config_test
+
+import (
+	"flag"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/abemedia/gocheck/internal/config"
+	"github.com/abemedia/gocheck/internal/skip"
+)
+
+const yamlConfig = `
+exclude:
+  - "**/mocks/**"
+analyzers:
+  fieldorder:
+    enabled: false
+  untested:
+    internal: true
+    interfaces: [fmt.Stringer, error]
+    min-ratio: 0.5
+overrides:
+  - path: pkg/api
+    analyzers:
+      untested:
+        internal: false
+        exclude: "*_gen.go"
+`
+
+const tomlConfig = `
+exclude = ["**/mocks/**"]
+
+[analyzers.fieldorder]
+enabled = false
+
+[analyzers.untested]
+internal = true
+interfaces = ["fmt.Stringer", "error"]
+min-ratio = 0.5
+
+[[overrides]]
+path = "pkg/api"
+
+[overrides.analyzers.untested]
+internal = false
+exclude = "*_gen.go"
+`
+
+func TestFind(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: ".gocheck.yaml", content: yamlConfig},
+		{name: "gocheck.toml", content: tomlConfig},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeFile(t, filepath.Join(root, "go.mod"), "module example.com/app\n")
+			writeFile(t, filepath.Join(root, tt.name), tt.content)
+			writeFile(t, filepath.Join(root, "pkg", "api", "api.go"), "package api\n")
+
+			cfg, err := config.Find(filepath.Join(root, "pkg", "api"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := &config.Config{
+				Root:    root,
+				Exclude: []string{"**/mocks/**"},
+				Analyzers: map[string]config.Options{
+					"fieldorder": {"enabled": false},
+					"untested":   {"internal": true, "interfaces": []any{"fmt.Stringer", "error"}, "min-ratio": 0.5},
+				},
+				Overrides: []config.Override{{
+					Path:      "pkg/api",
+					Analyzers: map[string]config.Options{"untested": {"internal": false, "exclude": "*_gen.go"}},
+				}},
+			}
+			if !reflect.DeepEqual(cfg, want) {
+				t.Errorf("Find() = %+v, want %+v", cfg, want)
+			}
+		})
+	}
+
+	t.Run("none", func(t *testing.T) {
+		root := t.TempDir()
+		writeFile(t, filepath.Join(root, "go.mod"), "module example.com/app\n")
+
+		cfg, err := config.Find(root)
+		if err != nil || cfg != nil {
+			t.Errorf("Find() = %v, %v, want nil, nil", cfg, err)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		root := t.TempDir()
+		writeFile(t, filepath.Join(root, "go.mod"), "module example.com/app\n")
+		writeFile(t, filepath.Join(root, ".gocheck.yaml"), "analyzers: [")
+
+		if _, err := config.Find(root); err == nil {
+			t.Error("Find() expected error")
+		}
+	})
+}
+
+func TestApply(t *testing.T) {
+	root := t.TempDir()
+	cfg, err := loadString(t, root, ".gocheck.yaml", yamlConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fieldorder := &analysis.Analyzer{Name: "fieldorder"}
+	var exclude skip.Exclude
+	fieldorder.Flags.Var(&exclude, "exclude", "")
+
+	var got []string
+	untested := &analysis.Analyzer{Name: "untested"}
+	internal := untested.Flags.Bool("internal", false, "")
+	interfaces := untested.Flags.String("interfaces", "", "")
+	untested.Flags.Float64("min-ratio", 0, "")
+	untestedExclude := &skip.Exclude{}
+	untested.Flags.Var(untestedExclude, "exclude", "")
+	untested.Run = func(*analysis.Pass) (any, error) {
+		got = append(got, untested.Flags.Lookup("internal").Value.String()+" "+untestedExclude.String())
+		return nil, nil
+	}
+
+	analyzers, err := cfg.Apply([]*analysis.Analyzer{fieldorder, untested}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(analyzers) != 1 || analyzers[0] != untested {
+		t.Fatalf("Apply() = %v, want [untested]", analyzers)
+	}
+	if !*internal || *interfaces != "fmt.Stringer,error" {
+		t.Errorf("internal = %v, interfaces = %q", *internal, *interfaces)
+	}
+	if got := untested.Flags.Lookup("min-ratio").Value.String(); got != "0.5" {
+		t.Errorf("min-ratio = %s, want 0.5", got)
+	}
+
+	for _, dir := range []string{"pkg/api/v1", "pkg", "pkg/api"} {
+		if _, err := untested.Run(newPass(t, filepath.Join(root, dir, "x.go"))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []string{
+		"false **/mocks/**,*_gen.go",
+		"true **/mocks/**",
+		"false **/mocks/**,*_gen.go",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("runs = %q, want %q", got, want)
+	}
+	if !*internal || untestedExclude.String() != "**/mocks/**" {
+		t.Errorf("flags not restored: internal = %v, exclude = %q", *internal, untestedExclude.String())
+	}
+}
+
+func TestApplyCommandLine(t *testing.T) {
+	root := t.TempDir()
+	cfg, err := loadString(t, root, ".gocheck.yaml", "overrides:\n  - path: pkg\n    analyzers:\n      untested:\n        min-ratio: 1\n        internal: true\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	untested := &analysis.Analyzer{Name: "untested"}
+	untested.Flags.Bool("internal", false, "")
+	minRatio := untested.Flags.Float64("min-ratio", 0, "")
+	untested.Run = func(*analysis.Pass) (any, error) {
+		got = append(got, untested.Flags.Lookup("internal").Value.String(), untested.Flags.Lookup("min-ratio").Value.String())
+		return nil, nil
+	}
+
+	set := map[string]bool{"untested.min-ratio": true}
+	if _, err := cfg.Apply([]*analysis.Analyzer{untested}, set); err != nil {
+		t.Fatal(err)
+	}
+
+	// Parse the command line after applying the configuration.
+	*minRatio = 0.25
+
+	if _, err := untested.Run(newPass(t, filepath.Join(root, "pkg", "x.go"))); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"true", "0.25"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("internal, min-ratio = %q, want %q", got, want)
+	}
+}
+
+func TestArgs(t *testing.T) {
+	cfg, err := loadString(t, t.TempDir(), ".gocheck.yaml", yamlConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args []string
+		want bool
+	}{
+		{args: nil, want: false},
+		{args: []string{"./..."}, want: false},
+		{args: []string{"-fieldorder", "./..."}, want: true},
+		{args: []string{"-fieldorder=true", "./..."}, want: true},
+	}
+
+	for _, tt := range tests {
+		fs := flag.NewFlagSet("gocheck", flag.ContinueOnError)
+		fieldorder := fs.Bool("fieldorder", false, "")
+		untested := fs.Bool("untested", false, "")
+
+		args := cfg.Args(tt.args)
+		if err := fs.Parse(args); err != nil {
+			t.Fatalf("Parse(%q): %v", args, err)
+		}
+		if *fieldorder != tt.want || *untested {
+			t.Errorf("Args(%q) = %q: fieldorder = %t, untested = %t, want %t, false", tt.args, args, *fieldorder, *untested, tt.want)
+		}
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "unknown analyzer", content: "analyzers:\n  unknown: {}\n"},
+		{name: "unknown option", content: "analyzers:\n  untested:\n    unknown: true\n"},
+		{name: "invalid value", content: "analyzers:\n  untested:\n    internal: maybe\n"},
+		{name: "invalid enabled", content: "analyzers:\n  untested:\n    enabled: 1\n"},
+		{name: "override enabled", content: "overrides:\n  - path: pkg\n    analyzers:\n      untested:\n        enabled: false\n"},
+		{name: "override absolute path", content: "overrides:\n  - path: /pkg\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := loadString(t, t.TempDir(), ".gocheck.yaml", tt.content)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			untested := &analysis.Analyzer{Name: "untested"}
+			untested.Flags.Bool("internal", false, "")
+
+			if _, err := cfg.Apply([]*analysis.Analyzer{untested}, nil); err == nil {
+				t.Error("Apply() expected error")
+			}
+		})
+	}
+}
+
+func loadString(t *testing.T, root, name, content string) (*config.Config, error) {
+	t.Helper()
+	path := filepath.Join(root, name)
+	writeFile(t, path, content)
+	return config.Load(path)
+}
+
+func newPass(t *testing.T, filename string) *analysis.Pass {
+	t.Helper()
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, "package x", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &analysis.Pass{Fset: fset, Files: []*ast.File{file}}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,69 @@
+package driver
+
+import (
+	"encoding/xml"
+	"io"
+)
+
+type checkstyleLog struct {
+	XMLName xml.Name         `xml:"checkstyle"`
+	Version string           `xml:"version,attr"`
+	Files   []checkstyleFile `xml:"file"`
+}
+
+type checkstyleFile struct {
+	Name   string            `xml:"name,attr"`
+	Errors []checkstyleError `xml:"error"`
+}
+
+type checkstyleError struct {
+	Line     int    `xml:"line,attr"`
+	Column   int    `xml:"column,attr"`
+	Severity string `xml:"severity,attr"`
+	Message  string `xml:"message,attr"`
+	Source   string `xml:"source,attr"`
+}
+
+// writeCheckstyle writes the diagnostics as a checkstyle XML report, with an
+// element per file and the analyzer's name as each error's source.
+func writeCheckstyle(w io.Writer, r *Results) error {
+	log := checkstyleLog{Version: "5.0"}
+
+	files := make(map[string]int)
+	for _, d := range r.Diagnostics {
+		pos := r.Position(d.Position)
+
+		i, ok := files[pos.Filename]
+		if !ok {
+			i = len(log.Files)
+			files[pos.Filename] = i
+			log.Files = append(log.Files, checkstyleFile{Name: pos.Filename})
+		}
+
+		log.Files[i].Errors = append(log.Files[i].Errors, checkstyleError{
+			Line:     pos.Line,
+			Column:   pos.Column,
+			Severity: "warning",
+			Message:  d.Message,
+			Source:   d.Analyzer,
+		})
+	}
+
+	return writeXML(w, log)
+}
+
+// writeXML writes an indented XML document.
+func writeXML(w io.Writer, v any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,208 @@
+// Package driver runs the analyzers for the output formats multichecker
+// doesn't support, such as SARIF, checkstyle, JUnit and GitHub Actions
+// annotations, and for plain text output using the result cache.
+package driver
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/abemedia/gocheck/check"
+	"github.com/abemedia/gocheck/internal/watch"
+)
+
+// Format returns the value of the -format flag in the command-line arguments,
+// or "text" if it isn't set. The arguments are parsed with the driver's flags,
+// so the values of the analyzers' flags aren't mistaken for packages.
+func Format(args []string, analyzers ...*analysis.Analyzer) string {
+	fs, opts := newFlagSet(analyzers, true)
+	fs.SetOutput(io.Discard)
+	_ = fs.Parse(args)
+
+	return *opts.format
+}
+
+// Flags returns the names of the flags set in the command-line arguments, such
+// as "fieldorder" or "untested.min-ratio", leaving the analyzers' flags
+// unchanged.
+func Flags(args []string, analyzers ...*analysis.Analyzer) map[string]bool {
+	fs, _ := newFlagSet(analyzers, true)
+	fs.SetOutput(io.Discard)
+	_ = fs.Parse(args)
+
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	return set
+}
+
+// Supports reports whether the driver handles the command-line arguments,
+// rather than multichecker. The driver handles runs on packages which only
+// use its own flags, leaving help, the vet tool protocol and flags such as
+// -fix to multichecker.
+func Supports(args []string, analyzers ...*analysis.Analyzer) bool {
+	fs, _ := newFlagSet(analyzers, true)
+	fs.SetOutput(io.Discard)
+	if err := fs.Parse(args); err != nil {
+		return false
+	}
+
+	args = fs.Args()
+	switch {
+	case len(args) == 0, args[0] == "help":
+		return false
+	case len(args) == 1 && strings.HasSuffix(args[0], ".cfg"):
+		return false // invoked by go vet -vettool
+	default:
+		return true
+	}
+}
+
+// Run runs the analyzers on the packages given in the arguments and writes the
+// diagnostics to stdout in the format given by the -format flag, returning the
+// number of diagnostics. The arguments mirror those of multichecker: each
+// analyzer is enabled or disabled by a flag of its name, and its flags are
+// prefixed with its name.
+func Run(args []string, stdout io.Writer, analyzers ...*analysis.Analyzer) (int, error) {
+	fs, opts := newFlagSet(analyzers, false)
+	if err := fs.Parse(args); err != nil {
+		return 0, err
+	}
+	if _, ok := reporters[*opts.format]; !ok {
+		return 0, fmt.Errorf("unknown format %q", *opts.format)
+	}
+
+	if err := SetTags(*opts.tags); err != nil {
+		return 0, err
+	}
+
+	analyzers = enabled(fs, analyzers, opts.enable)
+	checkOpts := check.Options{Analyzers: analyzers, Tests: *opts.tests, Cache: *opts.cache}
+
+	if *opts.watch {
+		if *opts.format != "text" {
+			return 0, fmt.Errorf("-watch doesn't support the %s format", *opts.format)
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		return 0, watch.Run(ctx, fs.Args(), checkOpts, stdout, watchInterval)
+	}
+
+	results, err := check.Run(context.Background(), fs.Args(), checkOpts)
+	if err != nil {
+		return 0, err
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return 0, err
+	}
+
+	r := &Results{Analyzers: analyzers, Diagnostics: results, Root: wd}
+	return len(results), Render(stdout, *opts.format, r)
+}
+
+// TagsUsage is the usage of the -tags flag.
+const TagsUsage = "comma-separated list of build tags to consider satisfied, as with go build"
+
+// SetTags adds the build tags to GOFLAGS, so they apply to the packages loaded
+// by the analyzers themselves too, such as the tests loaded by untested.
+func SetTags(tags string) error {
+	if tags == "" {
+		return nil
+	}
+	return os.Setenv("GOFLAGS", strings.TrimSpace(os.Getenv("GOFLAGS")+" -tags="+tags))
+}
+
+// watchInterval is how often -watch polls for changed files.
+const watchInterval = 500 * time.Millisecond
+
+// flags are the values of the driver's own flags.
+type flags struct {
+	format *string
+	tests  *bool
+	tags   *string
+	cache  *check.CacheMode
+	watch  *bool
+	enable map[string]*bool
+}
+
+// newFlagSet returns the flags accepted by the driver. If dryRun is set,
+// parsing the flags leaves the flags of the analyzers unchanged.
+func newFlagSet(analyzers []*analysis.Analyzer, dryRun bool) (*flag.FlagSet, *flags) {
+	fs := flag.NewFlagSet("gocheck", flag.ContinueOnError)
+
+	var cache check.CacheMode
+	opts := &flags{
+		format: fs.String("format", "text", "output format: "+strings.Join(Formats(), ", ")),
+		tests:  fs.Bool("test", true, "indicates whether test files should be analyzed, too"),
+		tags:   fs.String("tags", "", TagsUsage),
+		cache:  &cache,
+		watch:  fs.Bool("watch", false, "re-analyze packages as their files change, reporting new and resolved diagnostics"),
+		enable: make(map[string]*bool, len(analyzers)),
+	}
+	fs.Var(opts.cache, "cache", "result cache mode: off, read or readwrite")
+
+	for _, a := range analyzers {
+		opts.enable[a.Name] = fs.Bool(a.Name, false, "enable "+a.Name+" analysis")
+		a.Flags.VisitAll(func(f *flag.Flag) {
+			value := f.Value
+			if dryRun {
+				value = discardValue(value)
+			}
+			fs.Var(value, a.Name+"."+f.Name, f.Usage)
+		})
+	}
+
+	return fs, opts
+}
+
+// discardValue returns a flag value ignoring values set, but otherwise parsed
+// the same as v.
+func discardValue(v flag.Value) flag.Value {
+	if b, ok := v.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+		return discardBool{discard{v}}
+	}
+	return discard{v}
+}
+
+type discard struct{ flag.Value }
+
+func (discard) Set(string) error { return nil }
+
+type discardBool struct{ discard }
+
+func (discardBool) IsBoolFlag() bool { return true }
+
+// enabled returns the analyzers to run. As with multichecker, explicitly
+// enabling analyzers disables all others, otherwise all analyzers not
+// explicitly disabled run.
+func enabled(fs *flag.FlagSet, analyzers []*analysis.Analyzer, enable map[string]*bool) []*analysis.Analyzer {
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		if _, ok := enable[f.Name]; ok {
+			set[f.Name] = true
+		}
+	})
+
+	var explicit bool
+	for name := range set {
+		explicit = explicit || *enable[name]
+	}
+
+	return slices.DeleteFunc(slices.Clone(analyzers), func(a *analysis.Analyzer) bool {
+		if explicit {
+			return !*enable[a.Name]
+		}
+		return set[a.Name] && !*enable[a.Name]
+	})
+}
@@ -0,0 +1,307 @@
+package driver_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/abemedia/gocheck/check"
+	"github.com/abemedia/gocheck/internal/driver"
+)
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		format string
+		golden string
+	}{
+		{format: "sarif", golden: "a.sarif"},
+		{format: "checkstyle", golden: "a.checkstyle.xml"},
+		{format: "junit", golden: "a.junit.xml"},
+		{format: "github", golden: "a.github.txt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if _, err := driver.Run([]string{"-format=" + tt.format, "-cache=off", "-untested.internal", "./testdata/a"}, &buf, check.Analyzers()...); err != nil {
+				t.Fatal(err)
+			}
+
+			want, err := os.ReadFile(filepath.Join("testdata", tt.golden))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != string(want) {
+				t.Errorf("got:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestRunText(t *testing.T) {
+	var buf bytes.Buffer
+	n, err := driver.Run([]string{"-cache=off", "-untested.internal", "./testdata/a"}, &buf, check.Analyzers()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := filepath.Abs(filepath.Join("testdata", "a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := fmt.Sprintf("%[1]s:12:1: exported function \"Adult\" has no test\n"+
+		"%[1]s:14:26: struct literal fields are out of order\n"+
+		"%[1]s:16:1: unexported function \"unused\" is unreachable\n", filepath.Join(dir, "a.go"))
+	if got := buf.String(); n != 3 || got != want {
+		t.Errorf("got %d diagnostics:\n%s\nwant 3:\n%s", n, got, want)
+	}
+}
+
+func TestRunSARIFSchema(t *testing.T) {
+	var buf bytes.Buffer
+	if _, err := driver.Run([]string{"-format=sarif", "-cache=off", "-untested.internal", "./testdata/a"}, &buf, check.Analyzers()...); err != nil {
+		t.Fatal(err)
+	}
+
+	schema, err := jsonschema.NewCompiler().Compile(filepath.Join("testdata", "sarif-schema-2.1.0.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var log any
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatal(err)
+	}
+	if err := schema.Validate(log); err != nil {
+		t.Errorf("invalid SARIF: %v", err)
+	}
+}
+
+func TestRender(t *testing.T) {
+	results := &driver.Results{
+		Analyzers: []*analysis.Analyzer{{Name: "test"}},
+		Diagnostics: []check.Result{{
+			Analyzer: "test",
+			Position: token.Position{Filename: "/src/app/a,b.go", Offset: 55, Line: 2, Column: 6},
+			Message:  "100% broken:\nsee docs",
+		}},
+		Root: "/src",
+	}
+
+	var buf bytes.Buffer
+	if err := driver.Render(&buf, "github", results); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "::error file=app/a%2Cb.go,line=2,col=6,title=test::100%25 broken:%0Asee docs\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	driver.Register("count", func(w io.Writer, r *driver.Results) error {
+		_, err := fmt.Fprintln(w, len(r.Diagnostics))
+		return err
+	})
+	t.Cleanup(func() { driver.Register("count", nil) })
+	if !slices.Contains(driver.Formats(), "count") {
+		t.Errorf("Formats() = %v, want count", driver.Formats())
+	}
+
+	buf.Reset()
+	if err := driver.Render(&buf, "count", results); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != "1\n" {
+		t.Errorf("got %q, want %q", got, "1\n")
+	}
+
+	if err := driver.Render(&buf, "xml", results); err == nil {
+		t.Error("expected error for unknown format")
+	}
+
+	t.Run("Unregister", func(t *testing.T) {
+		driver.Register("count", nil)
+		if slices.Contains(driver.Formats(), "count") {
+			t.Errorf("Formats() = %v, want no count", driver.Formats())
+		}
+	})
+}
+
+func TestRunEnabled(t *testing.T) {
+	tests := []struct {
+		name  string
+		args  []string
+		rules []string
+	}{
+		{name: "default", args: nil, rules: []string{"fieldorder", "untested", "unreachable"}},
+		{name: "enable", args: []string{"-fieldorder"}, rules: []string{"fieldorder"}},
+		{name: "disable", args: []string{"-untested=false"}, rules: []string{"fieldorder", "unreachable"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			args := append([]string{"-format=sarif", "-cache=off", "-test=false"}, append(tt.args, "./testdata/a")...)
+			if _, err := driver.Run(args, &buf, check.Analyzers()...); err != nil {
+				t.Fatal(err)
+			}
+
+			var log struct {
+				Runs []struct {
+					Tool struct {
+						Driver struct {
+							Rules []struct{ ID string }
+						}
+					}
+				}
+			}
+			if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+				t.Fatal(err)
+			}
+
+			var rules []string
+			for _, rule := range log.Runs[0].Tool.Driver.Rules {
+				rules = append(rules, rule.ID)
+			}
+			if !slices.Equal(rules, tt.rules) {
+				t.Errorf("rules = %v, want %v", rules, tt.rules)
+			}
+		})
+	}
+}
+
+func TestRunTags(t *testing.T) {
+	t.Setenv("GOFLAGS", "")
+
+	args := []string{"-cache=off", "-untested.internal", "./testdata/tags"}
+	if n, err := driver.Run(args, io.Discard, check.Analyzers()...); err != nil || n != 1 {
+		t.Fatalf("Run(%q) = %d, %v, want 1 diagnostic", args, n, err)
+	}
+
+	// The tests guarded by the tag cover the function.
+	args = append([]string{"-tags=integration"}, args...)
+	if n, err := driver.Run(args, io.Discard, check.Analyzers()...); err != nil || n != 0 {
+		t.Fatalf("Run(%q) = %d, %v, want no diagnostics", args, n, err)
+	}
+}
+
+func TestRunCacheDefault(t *testing.T) {
+	// Keep using Go's caches while moving the user cache directory.
+	for _, env := range []string{"GOCACHE", "GOMODCACHE"} {
+		out, err := exec.Command("go", "env", env).Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Setenv(env, strings.TrimSpace(string(out)))
+	}
+
+	dir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", dir)
+	t.Setenv("HOME", dir)
+	t.Setenv("LocalAppData", dir)
+
+	args := []string{"-untested.internal", "./testdata/a"}
+	if _, err := driver.Run(args, io.Discard, check.Analyzers()...); err != nil {
+		t.Fatal(err)
+	}
+
+	// The cache is off unless asked for.
+	cacheDir, err := check.DefaultCacheDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(cacheDir); !os.IsNotExist(err) {
+		t.Errorf("Run(%q) created the cache directory: %v", args, err)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	for _, args := range [][]string{
+		{"-format=xml", "./testdata/a"},
+		{"-format=sarif", "-unknown", "./testdata/a"},
+		{"-format=sarif", "-cache=off", "./testdata/missing"},
+		{"-cache=always", "./testdata/a"},
+		{"-watch", "-format=sarif", "./testdata/a"},
+	} {
+		if _, err := driver.Run(args, &bytes.Buffer{}, check.Analyzers()...); err == nil {
+			t.Errorf("Run(%q) expected error", args)
+		}
+	}
+}
+
+func TestFlags(t *testing.T) {
+	analyzers := check.Analyzers()
+	got := driver.Flags([]string{"-fieldorder", "-untested.min-ratio", "0.5", "-format=sarif", "./..."}, analyzers...)
+
+	want := map[string]bool{"fieldorder": true, "untested.min-ratio": true, "format": true}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Flags() = %v, want %v", got, want)
+	}
+	if got := analyzers[1].Flags.Lookup("min-ratio").Value.String(); got != "0" {
+		t.Errorf("untested.min-ratio = %s, want 0", got)
+	}
+}
+
+func TestSupports(t *testing.T) {
+	tests := []struct {
+		args []string
+		want bool
+	}{
+		{args: nil, want: false},
+		{args: []string{"help"}, want: false},
+		{args: []string{"help", "untested"}, want: false},
+		{args: []string{"vet.cfg"}, want: false},
+		{args: []string{"-flags"}, want: false},
+		{args: []string{"-fix", "./..."}, want: false},
+		{args: []string{"./..."}, want: true},
+		{args: []string{"-cache=off", "-test=false", "./..."}, want: true},
+		{args: []string{"-watch", "./..."}, want: true},
+		{args: []string{"-untested.internal", "-fieldorder=false", "./a", "./b"}, want: true},
+	}
+
+	for _, tt := range tests {
+		if got := driver.Supports(tt.args, check.Analyzers()...); got != tt.want {
+			t.Errorf("Supports(%q) = %t, want %t", tt.args, got, tt.want)
+		}
+	}
+
+	// Checking the arguments leaves the analyzers' flags unchanged.
+	analyzers := check.Analyzers()
+	driver.Supports([]string{"-untested.internal", "./..."}, analyzers...)
+	if got := analyzers[1].Flags.Lookup("internal").Value.String(); got != "false" {
+		t.Errorf("untested.internal = %s, want false", got)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{args: nil, want: "text"},
+		{args: []string{"./..."}, want: "text"},
+		{args: []string{"-format=sarif", "./..."}, want: "sarif"},
+		{args: []string{"--format", "sarif", "./..."}, want: "sarif"},
+		{args: []string{"-untested.internal", "-format=sarif"}, want: "sarif"},
+		{args: []string{"-untested.summary", "s.json", "-format=sarif", "./..."}, want: "sarif"},
+		{args: []string{"-untested.min-ratio=0.5", "--format", "junit", "./..."}, want: "junit"},
+		{args: []string{"./...", "-format=sarif"}, want: "text"},
+		{args: []string{"--", "-format=sarif"}, want: "text"},
+	}
+
+	for _, tt := range tests {
+		if got := driver.Format(tt.args, check.Analyzers()...); got != tt.want {
+			t.Errorf("Format(%q) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
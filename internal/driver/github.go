@@ -0,0 +1,45 @@
+package driver
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// writeGitHub writes the diagnostics as GitHub Actions workflow commands,
+// which annotate the lines of the diagnostics in pull requests.
+func writeGitHub(w io.Writer, r *Results) error {
+	for _, d := range r.Diagnostics {
+		pos := r.Position(d.Position)
+
+		props := fmt.Sprintf("file=%s,line=%d,col=%d", escapeProperty(pos.Filename), pos.Line, pos.Column)
+		if d.End.IsValid() {
+			props += fmt.Sprintf(",endLine=%d", d.End.Line)
+			if d.End.Line == pos.Line {
+				props += fmt.Sprintf(",endColumn=%d", d.End.Column)
+			}
+		}
+		props += ",title=" + escapeProperty(d.Analyzer)
+
+		if _, err := fmt.Fprintf(w, "::error %s::%s\n", props, escapeData(d.Message)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+var (
+	dataEscaper     = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+	propertyEscaper = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C")
+)
+
+// escapeData escapes the message of a workflow command.
+func escapeData(s string) string {
+	return dataEscaper.Replace(s)
+}
+
+// escapeProperty escapes a property value of a workflow command.
+func escapeProperty(s string) string {
+	return propertyEscaper.Replace(s)
+}
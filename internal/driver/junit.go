@@ -0,0 +1,67 @@
+package driver
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string       `xml:"name,attr"`
+	ClassName string       `xml:"classname,attr"`
+	Failure   junitFailure `xml:"failure"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// writeJUnit writes the diagnostics as a JUnit XML report, with a test suite
+// per analyzer and a failed test case per diagnostic.
+func writeJUnit(w io.Writer, r *Results) error {
+	report := junitTestSuites{Name: "gocheck"}
+
+	suites := make(map[string]int)
+	for _, a := range r.Analyzers {
+		suites[a.Name] = len(report.Suites)
+		report.Suites = append(report.Suites, junitTestSuite{Name: a.Name})
+	}
+
+	for _, d := range r.Diagnostics {
+		pos := r.Position(d.Position)
+		suite := &report.Suites[suites[d.Analyzer]]
+
+		suite.TestCases = append(suite.TestCases, junitTestCase{
+			Name:      pos.String(),
+			ClassName: d.Analyzer,
+			Failure: junitFailure{
+				Message: d.Message,
+				Type:    d.Analyzer,
+				Text:    fmt.Sprintf("%s: %s", pos, d.Message),
+			},
+		})
+		suite.Tests++
+		suite.Failures++
+		report.Tests++
+		report.Failures++
+	}
+
+	return writeXML(w, report)
+}
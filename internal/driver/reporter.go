@@ -0,0 +1,67 @@
+package driver
+
+import (
+	"fmt"
+	"go/token"
+	"io"
+	"maps"
+	"path/filepath"
+	"slices"
+
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/abemedia/gocheck/check"
+)
+
+// Results are the diagnostics of a run of the analyzers.
+type Results struct {
+	Analyzers   []*analysis.Analyzer
+	Diagnostics []check.Result
+
+	// Root is the directory paths are written relative to where possible.
+	Root string
+}
+
+// Position returns the position with its filename relative to the root if the
+// file is within it.
+func (r *Results) Position(pos token.Position) token.Position {
+	if rel, err := filepath.Rel(r.Root, pos.Filename); err == nil && filepath.IsLocal(rel) {
+		pos.Filename = filepath.ToSlash(rel)
+	}
+	return pos
+}
+
+// A Reporter writes results in an output format.
+type Reporter func(w io.Writer, r *Results) error
+
+var reporters = map[string]Reporter{
+	"checkstyle": writeCheckstyle,
+	"github":     writeGitHub,
+	"junit":      writeJUnit,
+	"sarif":      writeSARIF,
+	"text":       writeText,
+}
+
+// Register registers the reporter for an output format, replacing any
+// reporter previously registered for it. A nil reporter removes the format.
+func Register(format string, reporter Reporter) {
+	if reporter == nil {
+		delete(reporters, format)
+		return
+	}
+	reporters[format] = reporter
+}
+
+// Formats returns the names of the output formats supported by the driver.
+func Formats() []string {
+	return slices.Sorted(maps.Keys(reporters))
+}
+
+// Render writes the results in the given format.
+func Render(w io.Writer, format string, r *Results) error {
+	reporter, ok := reporters[format]
+	if !ok {
+		return fmt.Errorf("unknown format %q", format)
+	}
+	return reporter(w, r)
+}
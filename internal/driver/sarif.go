@@ -0,0 +1,247 @@
+package driver
+
+import (
+	"encoding/json"
+	"go/token"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/abemedia/gocheck/check"
+)
+
+const (
+	sarifSchema  = "https://docs.oasis-open.org/sarif/sarif/v2.1.0/errata01/os/schemas/sarif-schema-2.1.0.json"
+	sarifVersion = "2.1.0"
+	sarifSrcRoot = "%SRCROOT%"
+	toolURI      = "https://github.com/abemedia/gocheck"
+)
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+	FullDescription  sarifMessage `json:"fullDescription"`
+	Help             sarifMessage `json:"help"`
+	HelpURI          string       `json:"helpUri"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID           string          `json:"ruleId"`
+	RuleIndex        int             `json:"ruleIndex"`
+	Level            string          `json:"level"`
+	Message          sarifMessage    `json:"message"`
+	Locations        []sarifLocation `json:"locations"`
+	RelatedLocations []sarifLocation `json:"relatedLocations,omitempty"`
+	Fixes            []sarifFix      `json:"fixes,omitempty"`
+}
+
+type sarifLocation struct {
+	ID               *int                  `json:"id,omitempty"`
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+	Message          *sarifMessage         `json:"message,omitempty"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+// sarifRegion is a range of a file. Columns count UTF-16 code units, as is
+// the default in SARIF, and the end column is exclusive.
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndLine     int `json:"endLine,omitempty"`
+	EndColumn   int `json:"endColumn,omitempty"`
+}
+
+type sarifFix struct {
+	Description     sarifMessage          `json:"description"`
+	ArtifactChanges []sarifArtifactChange `json:"artifactChanges"`
+}
+
+type sarifArtifactChange struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Replacements     []sarifReplacement    `json:"replacements"`
+}
+
+type sarifReplacement struct {
+	DeletedRegion   sarifRegion   `json:"deletedRegion"`
+	InsertedContent *sarifMessage `json:"insertedContent,omitempty"`
+}
+
+// writeSARIF writes the diagnostics as a SARIF 2.1.0 log with a rule for each
+// analyzer. Suggested fixes are written as fixes replacing regions of files.
+func writeSARIF(w io.Writer, r *Results) error {
+	s := &sarifWriter{root: r.Root, files: make(map[string][]byte)}
+
+	rules := make([]sarifRule, len(r.Analyzers))
+	ruleIndex := make(map[string]int, len(r.Analyzers))
+	for i, a := range r.Analyzers {
+		title, _, _ := strings.Cut(a.Doc, "\n\n")
+		rules[i] = sarifRule{
+			ID:               a.Name,
+			ShortDescription: sarifMessage{Text: title},
+			FullDescription:  sarifMessage{Text: a.Doc},
+			Help:             sarifMessage{Text: a.Doc},
+			HelpURI:          toolURI + "#" + a.Name,
+		}
+		ruleIndex[a.Name] = i
+	}
+
+	results := make([]sarifResult, 0, len(r.Diagnostics))
+	for _, d := range r.Diagnostics {
+		result := sarifResult{
+			RuleID:    d.Analyzer,
+			RuleIndex: ruleIndex[d.Analyzer],
+			Level:     "warning",
+			Message:   sarifMessage{Text: d.Message},
+			Locations: []sarifLocation{{PhysicalLocation: s.location(d.Position, d.End)}},
+		}
+
+		for i, related := range d.Related {
+			result.RelatedLocations = append(result.RelatedLocations, sarifLocation{
+				ID:               &i,
+				PhysicalLocation: s.location(related.Position, related.End),
+				Message:          &sarifMessage{Text: related.Message},
+			})
+		}
+
+		for _, fix := range d.Fixes {
+			result.Fixes = append(result.Fixes, s.fix(fix))
+		}
+
+		results = append(results, result)
+	}
+
+	log := sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs: []sarifRun{{
+			Tool:    sarifTool{Driver: sarifDriver{Name: "gocheck", InformationURI: toolURI, Rules: rules}},
+			Results: results,
+		}},
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(log)
+}
+
+// sarifWriter converts positions to SARIF locations.
+type sarifWriter struct {
+	root  string
+	files map[string][]byte
+}
+
+// fix converts a suggested fix, grouping its edits by file.
+func (s *sarifWriter) fix(fix check.Fix) sarifFix {
+	result := sarifFix{Description: sarifMessage{Text: fix.Message}}
+
+	changes := make(map[string]int)
+	for _, edit := range fix.Edits {
+		loc := s.location(edit.Position, edit.End)
+
+		i, ok := changes[loc.ArtifactLocation.URI]
+		if !ok {
+			i = len(result.ArtifactChanges)
+			changes[loc.ArtifactLocation.URI] = i
+			result.ArtifactChanges = append(result.ArtifactChanges, sarifArtifactChange{ArtifactLocation: loc.ArtifactLocation})
+		}
+
+		replacement := sarifReplacement{DeletedRegion: loc.Region}
+		if len(edit.NewText) > 0 {
+			replacement.InsertedContent = &sarifMessage{Text: edit.NewText}
+		}
+		result.ArtifactChanges[i].Replacements = append(result.ArtifactChanges[i].Replacements, replacement)
+	}
+
+	return result
+}
+
+// location returns the physical location of the range from start to end, which
+// may be invalid.
+func (s *sarifWriter) location(start, end token.Position) sarifPhysicalLocation {
+	loc := sarifPhysicalLocation{
+		ArtifactLocation: s.artifact(start.Filename),
+		Region:           sarifRegion{StartLine: start.Line, StartColumn: s.column(start)},
+	}
+
+	if end.IsValid() {
+		loc.Region.EndLine = end.Line
+		loc.Region.EndColumn = s.column(end)
+	}
+
+	return loc
+}
+
+// artifact returns the location of a file, relative to the source root if the
+// file is within it.
+func (s *sarifWriter) artifact(filename string) sarifArtifactLocation {
+	if rel, err := filepath.Rel(s.root, filename); err == nil && filepath.IsLocal(rel) {
+		return sarifArtifactLocation{URI: filepath.ToSlash(rel), URIBaseID: sarifSrcRoot}
+	}
+
+	u := url.URL{Scheme: "file", Path: filepath.ToSlash(filename)}
+	if !strings.HasPrefix(u.Path, "/") {
+		u.Path = "/" + u.Path
+	}
+	return sarifArtifactLocation{URI: u.String()}
+}
+
+// column returns the 1-based column of a position in UTF-16 code units. It
+// falls back to the byte column if the file can't be read.
+func (s *sarifWriter) column(pos token.Position) int {
+	src, ok := s.files[pos.Filename]
+	if !ok {
+		src, _ = os.ReadFile(pos.Filename)
+		s.files[pos.Filename] = src
+	}
+
+	start := pos.Offset - (pos.Column - 1)
+	if start < 0 || pos.Offset > len(src) {
+		return pos.Column
+	}
+
+	col := 1
+	for line := src[start:pos.Offset]; len(line) > 0; {
+		r, size := utf8.DecodeRune(line)
+		col += utf16.RuneLen(r)
+		line = line[size:]
+	}
+	return col
+}
@@ -0,0 +1,16 @@
+package a
+
+type Person struct {
+	Name string
+	Age  int
+}
+
+// Greeting is tested.
+func Greeting() string { return "héllo 👋" }
+
+// Adult has no test.
+func Adult(p Person) bool { return p.Age >= 18 }
+
+var _, _ = "👋", Person{Age: 30, Name: "John"}
+
+func unused() {}
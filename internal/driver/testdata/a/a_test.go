@@ -0,0 +1,9 @@
+package a
+
+import "testing"
+
+func TestGreeting(t *testing.T) {
+	if Greeting() == "" {
+		t.Error("empty greeting")
+	}
+}
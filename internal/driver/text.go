@@ -0,0 +1,22 @@
+package driver
+
+import (
+	"fmt"
+	"io"
+)
+
+// writeText writes the diagnostics as plain text, in the same form as
+// multichecker.
+func writeText(w io.Writer, r *Results) error {
+	for _, d := range r.Diagnostics {
+		if _, err := fmt.Fprintf(w, "%s: %s\n", d.Position, d.Message); err != nil {
+			return err
+		}
+		for _, related := range d.Related {
+			if _, err := fmt.Fprintf(w, "%s: \t%s\n", related.Position, related.Message); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,447 @@
+// Package lsp implements the gocheck lsp command, a Language Server Protocol
+// server publishing the diagnostics of the analyzers and offering their
+// suggested fixes as quick fixes.
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/token"
+	"io"
+	"maps"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/abemedia/gocheck/check"
+)
+
+// Serve serves the Language Server Protocol over the given reader and writer,
+// usually stdin and stdout, until the client sends the exit notification or
+// closes the input. The package of each opened file is analyzed in the
+// background when it's opened and whenever one of its files is saved, canceling
+// any earlier analysis of it still running. The diagnostics of files with
+// unsaved changes are withdrawn until they're saved, as their positions refer to
+// the contents on disk.
+func Serve(ctx context.Context, r io.Reader, w io.Writer, analyzers ...*analysis.Analyzer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	s := &server{
+		conn:      newConn(r, w),
+		analyzers: analyzers,
+		cancel:    cancel,
+		running:   make(map[string]context.CancelFunc),
+		analyzed:  make(map[string]bool),
+		published: make(map[string][]string),
+		results:   make(map[string]*fileResults),
+		buffers:   make(map[string]string),
+	}
+	defer s.wg.Wait()
+	defer cancel()
+
+	for {
+		msg, err := s.conn.read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		var rpcErr *rpcError
+		if errors.As(err, &rpcErr) {
+			if err := s.conn.reply(nil, nil, rpcErr); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if msg.Method == "exit" {
+			return nil
+		}
+
+		result, err := s.handle(ctx, msg)
+		if msg.ID == nil {
+			// Notifications have no response, so errors are shown to the user.
+			if err != nil {
+				s.showError(err)
+			}
+			continue
+		}
+		if err := s.conn.reply(msg.ID, result, err); err != nil {
+			return err
+		}
+	}
+}
+
+// server holds the state of a language server session. The mutex guards the
+// state and the order of published diagnostics, and is held while handling a
+// message or publishing the results of an analysis.
+type server struct {
+	conn      *conn
+	analyzers []*analysis.Analyzer
+	cancel    context.CancelFunc // cancels all analyses
+	wg        sync.WaitGroup     // running analyses
+
+	mu        sync.Mutex
+	running   map[string]context.CancelFunc // running analyses per package directory
+	analyzed  map[string]bool               // analyzed package directories
+	published map[string][]string           // files with diagnostics per package directory
+	results   map[string]*fileResults       // results per file
+	buffers   map[string]string             // contents of open files
+}
+
+// fileResults holds the results of a file and the contents they refer to.
+type fileResults struct {
+	lines   *lineReader
+	results []check.Result
+}
+
+// handle handles a request or notification.
+func (s *server) handle(ctx context.Context, msg *message) (any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch msg.Method {
+	case "initialize":
+		return initializeResult{
+			Capabilities: serverCapabilities{
+				TextDocumentSync:   textDocumentSyncOptions{OpenClose: true, Change: syncFull, Save: true},
+				CodeActionProvider: codeActionOptions{CodeActionKinds: []string{quickFix}},
+			},
+			ServerInfo: serverInfo{Name: "gocheck"},
+		}, nil
+
+	case "shutdown":
+		s.cancel()
+		return nil, nil
+
+	case "textDocument/didOpen":
+		var params didOpenParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+		filename, err := uriToPath(params.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.edit(filename, &params.TextDocument.Text); err != nil {
+			return nil, err
+		}
+		dir := filepath.Dir(filename)
+		if _, ok := s.running[dir]; !ok && !s.analyzed[dir] {
+			s.analyze(ctx, dir)
+		}
+		return nil, nil
+
+	case "textDocument/didChange":
+		var params didChangeParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+		filename, err := uriToPath(params.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+		if len(params.ContentChanges) == 0 {
+			return nil, nil
+		}
+		return nil, s.edit(filename, &params.ContentChanges[len(params.ContentChanges)-1].Text)
+
+	case "textDocument/didClose":
+		var params textDocumentParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+		filename, err := uriToPath(params.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+		return nil, s.edit(filename, nil)
+
+	case "textDocument/didSave":
+		var params textDocumentParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+		filename, err := uriToPath(params.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+		s.analyze(ctx, filepath.Dir(filename))
+		return nil, nil
+
+	case "textDocument/codeAction":
+		var params codeActionParams
+		if err := unmarshalParams(msg, &params); err != nil {
+			return nil, err
+		}
+		filename, err := uriToPath(params.TextDocument.URI)
+		if err != nil {
+			return nil, err
+		}
+		return s.codeActions(filename, params.Range), nil
+
+	default:
+		if msg.ID == nil {
+			return nil, nil
+		}
+		return nil, &rpcError{Code: codeMethodNotFound, Message: "method not found: " + msg.Method}
+	}
+}
+
+// analyze starts analyzing the package in dir, canceling any analysis of it
+// still running. It must be called with the mutex held.
+func (s *server) analyze(ctx context.Context, dir string) {
+	if cancel, ok := s.running[dir]; ok {
+		cancel()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	s.running[dir] = cancel
+
+	s.wg.Go(func() {
+		results, err := check.Run(ctx, []string{"."}, check.Options{Analyzers: s.analyzers, Dir: dir, Tests: true})
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		// The analysis was superseded or the server is shutting down.
+		if ctx.Err() != nil {
+			return
+		}
+		delete(s.running, dir)
+		cancel()
+
+		if err != nil {
+			s.showError(err)
+			return
+		}
+		if err := s.publish(dir, results); err != nil {
+			s.showError(err)
+		}
+	})
+}
+
+// publish publishes the diagnostics of the package in dir, clearing those of
+// files which no longer have any.
+func (s *server) publish(dir string, results []check.Result) error {
+	s.analyzed[dir] = true
+
+	byFile := make(map[string][]check.Result)
+	for _, r := range results {
+		byFile[r.Position.Filename] = append(byFile[r.Position.Filename], r)
+	}
+
+	for _, filename := range s.published[dir] {
+		if _, ok := byFile[filename]; !ok {
+			byFile[filename] = nil
+		}
+	}
+
+	s.published[dir] = s.published[dir][:0]
+	for _, filename := range slices.Sorted(maps.Keys(byFile)) {
+		results := byFile[filename]
+		if results == nil {
+			delete(s.results, filename)
+		} else {
+			s.results[filename] = &fileResults{lines: newLineReader(filename), results: results}
+			s.published[dir] = append(s.published[dir], filename)
+		}
+
+		if err := s.publishFile(filename); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// publishFile publishes the diagnostics of a file, or none if it has unsaved
+// changes.
+func (s *server) publishFile(filename string) error {
+	diags := []diagnostic{}
+	if f := s.results[filename]; f != nil && !s.modified(filename, f.lines.src) {
+		for _, r := range f.results {
+			diags = append(diags, toDiagnostic(f.lines, r))
+		}
+	}
+
+	params := publishDiagnosticsParams{URI: pathToURI(filename), Diagnostics: diags}
+	return s.conn.notify("textDocument/publishDiagnostics", params)
+}
+
+// edit records the contents of an open file, or that it was closed if text is
+// nil, republishing its diagnostics if that hides or restores them.
+func (s *server) edit(filename string, text *string) error {
+	f := s.results[filename]
+	hidden := f != nil && s.modified(filename, f.lines.src)
+
+	if text == nil {
+		delete(s.buffers, filename)
+	} else {
+		s.buffers[filename] = *text
+	}
+
+	if f == nil || s.modified(filename, f.lines.src) == hidden {
+		return nil
+	}
+	return s.publishFile(filename)
+}
+
+// modified reports whether a file is open with contents other than src.
+func (s *server) modified(filename string, src []byte) bool {
+	text, ok := s.buffers[filename]
+	return ok && text != string(src)
+}
+
+// codeActions returns quick fixes for the suggested fixes of the diagnostics
+// overlapping the range. Fixes editing files which don't exist or have unsaved
+// changes are left out.
+func (s *server) codeActions(filename string, rng lspRange) []codeAction {
+	actions := []codeAction{}
+
+	f := s.results[filename]
+	if f == nil || s.modified(filename, f.lines.src) {
+		return actions
+	}
+
+	for _, r := range f.results {
+		diag := toDiagnostic(f.lines, r)
+		if before(diag.Range.End, rng.Start) || before(rng.End, diag.Range.Start) {
+			continue
+		}
+
+	fixes:
+		for _, fix := range r.Fixes {
+			changes := make(map[string][]textEdit)
+			for _, edit := range fix.Edits {
+				lines := f.lines
+				if edit.Position.Filename != filename {
+					src, err := os.ReadFile(edit.Position.Filename)
+					if err != nil || s.modified(edit.Position.Filename, src) {
+						continue fixes
+					}
+					lines = &lineReader{src: src}
+				}
+				uri := pathToURI(edit.Position.Filename)
+				changes[uri] = append(changes[uri], textEdit{Range: lines.toRange(edit.Position, edit.End), NewText: edit.NewText})
+			}
+
+			actions = append(actions, codeAction{
+				Title:       fix.Message,
+				Kind:        quickFix,
+				Diagnostics: []diagnostic{diag},
+				Edit:        workspaceEdit{Changes: changes},
+			})
+		}
+	}
+
+	return actions
+}
+
+// showError shows an error message to the user.
+func (s *server) showError(err error) {
+	_ = s.conn.notify("window/showMessage", map[string]any{"type": 1, "message": "gocheck: " + err.Error()})
+}
+
+// toDiagnostic converts a result to an LSP diagnostic.
+func toDiagnostic(lines *lineReader, r check.Result) diagnostic {
+	end := r.End
+	if !end.IsValid() {
+		end = r.Position
+	}
+
+	diag := diagnostic{
+		Range:    lines.toRange(r.Position, end),
+		Severity: severityWarning,
+		Code:     r.Analyzer,
+		Source:   "gocheck",
+		Message:  r.Message,
+	}
+	if r.URL != "" {
+		diag.CodeDescription = &codeDescription{Href: r.URL}
+	}
+
+	return diag
+}
+
+// before reports whether position a is before b.
+func before(a, b position) bool {
+	return a.Line < b.Line || a.Line == b.Line && a.Character < b.Character
+}
+
+// lineReader converts byte-based positions in a file to LSP positions, which
+// count UTF-16 code units.
+type lineReader struct {
+	src []byte
+}
+
+func newLineReader(filename string) *lineReader {
+	src, _ := os.ReadFile(filename)
+	return &lineReader{src: src}
+}
+
+func (l *lineReader) toRange(start, end token.Position) lspRange {
+	return lspRange{Start: l.toPosition(start), End: l.toPosition(end)}
+}
+
+// toPosition converts a position, falling back to the byte column if the file
+// can't be read.
+func (l *lineReader) toPosition(pos token.Position) position {
+	p := position{Line: max(pos.Line-1, 0), Character: max(pos.Column-1, 0)}
+
+	start := pos.Offset - (pos.Column - 1)
+	if start < 0 || pos.Offset > len(l.src) {
+		return p
+	}
+
+	p.Character = 0
+	for line := l.src[start:pos.Offset]; len(line) > 0; {
+		r, size := utf8.DecodeRune(line)
+		p.Character += utf16.RuneLen(r)
+		line = line[size:]
+	}
+	return p
+}
+
+// unmarshalParams decodes the parameters of a message.
+func unmarshalParams(msg *message, v any) error {
+	if err := json.Unmarshal(msg.Params, v); err != nil {
+		return &rpcError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+// uriToPath converts a file URI to a path.
+func uriToPath(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "file" {
+		return "", fmt.Errorf("unsupported URI %q", uri)
+	}
+
+	path := filepath.FromSlash(u.Path)
+	// Windows paths are written as file:///C:/path.
+	if len(path) > 2 && path[0] == filepath.Separator && path[2] == ':' {
+		path = path[1:]
+	}
+	return path, nil
+}
+
+// pathToURI converts a path to a file URI.
+func pathToURI(path string) string {
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
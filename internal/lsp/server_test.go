@@ -0,0 +1,283 @@
+package lsp_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/abemedia/gocheck/check"
+	"github.com/abemedia/gocheck/internal/lsp"
+)
+
+// client is an in-process LSP client.
+type client struct {
+	t   *testing.T
+	w   io.Writer
+	r   *textproto.Reader
+	ids int
+}
+
+func (c *client) send(method string, id int, params any) {
+	c.t.Helper()
+	msg := map[string]any{"jsonrpc": "2.0", "method": method, "params": params}
+	if id > 0 {
+		msg["id"] = id
+	}
+	body, err := json.Marshal(msg)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if _, err := fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n%s", len(body), body); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// call sends a request and decodes its result.
+func (c *client) call(method string, params, result any) {
+	c.t.Helper()
+	c.ids++
+	c.send(method, c.ids, params)
+
+	var msg struct {
+		ID     int             `json:"id"`
+		Result json.RawMessage `json:"result"`
+		Error  *struct{ Message string }
+	}
+	c.read(&msg)
+	if msg.Error != nil {
+		c.t.Fatalf("%s: %s", method, msg.Error.Message)
+	}
+	if msg.ID != c.ids {
+		c.t.Fatalf("%s: got response to %d, want %d", method, msg.ID, c.ids)
+	}
+	if result != nil {
+		if err := json.Unmarshal(msg.Result, result); err != nil {
+			c.t.Fatal(err)
+		}
+	}
+}
+
+func (c *client) read(v any) {
+	c.t.Helper()
+	header, err := c.r.ReadMIMEHeader()
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	n, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	body := make([]byte, n)
+	if _, err := io.ReadFull(c.r.R, body); err != nil {
+		c.t.Fatal(err)
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// diagnostics reads the diagnostics published for a file.
+func (c *client) diagnostics(uri string) []diagnostic {
+	c.t.Helper()
+	var publish struct {
+		Method string
+		Params struct {
+			URI         string
+			Diagnostics []diagnostic
+		}
+	}
+	c.read(&publish)
+	if publish.Method != "textDocument/publishDiagnostics" || publish.Params.URI != uri {
+		c.t.Fatalf("got %s for %s, want textDocument/publishDiagnostics for %s", publish.Method, publish.Params.URI, uri)
+	}
+	return publish.Params.Diagnostics
+}
+
+type codeAction struct {
+	Title string
+	Kind  string
+	Edit  struct {
+		Changes map[string][]struct {
+			Range   lspRange
+			NewText string
+		}
+	}
+}
+
+// codeActions requests the code actions for a range of a file.
+func (c *client) codeActions(uri string, rng lspRange) []codeAction {
+	c.t.Helper()
+	var actions []codeAction
+	c.call("textDocument/codeAction", map[string]any{
+		"textDocument": map[string]any{"uri": uri},
+		"range":        rng,
+		"context":      map[string]any{"diagnostics": []any{}},
+	}, &actions)
+	return actions
+}
+
+type diagnostic struct {
+	Range   lspRange `json:"range"`
+	Code    string   `json:"code"`
+	Message string   `json:"message"`
+}
+
+type lspRange struct {
+	Start struct{ Line, Character int } `json:"start"`
+	End   struct{ Line, Character int } `json:"end"`
+}
+
+func TestServe(t *testing.T) {
+	analyzers := check.Analyzers()
+	for _, a := range analyzers {
+		if a.Name == "untested" {
+			a.Flags.Set("internal", "true")
+		}
+	}
+
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- lsp.Serve(context.Background(), inR, outW, analyzers...)
+		outW.Close()
+	}()
+
+	c := &client{t: t, w: inW, r: textproto.NewReader(bufio.NewReader(outR))}
+
+	var init struct {
+		Capabilities struct {
+			CodeActionProvider struct{ CodeActionKinds []string }
+		}
+	}
+	c.call("initialize", map[string]any{"processId": nil, "rootUri": nil, "capabilities": map[string]any{}}, &init)
+	if kinds := init.Capabilities.CodeActionProvider.CodeActionKinds; len(kinds) != 1 || kinds[0] != "quickfix" {
+		t.Errorf("codeActionKinds = %v, want [quickfix]", kinds)
+	}
+	c.send("initialized", 0, map[string]any{})
+
+	path, err := filepath.Abs(filepath.Join("testdata", "a", "a.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uri := (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+
+	c.send("textDocument/didOpen", 0, map[string]any{"textDocument": map[string]any{"uri": uri, "text": string(src)}})
+
+	diags := c.diagnostics(uri)
+	if len(diags) != 2 {
+		t.Fatalf("got %d diagnostics, want 2: %+v", len(diags), diags)
+	}
+	if d := diags[0]; d.Code != "untested" || d.Range.Start.Line != 8 || d.Range.Start.Character != 0 {
+		t.Errorf("diagnostics[0] = %+v", d)
+	}
+	// The emoji preceding the literal is two UTF-16 code units but four bytes.
+	if d := diags[1]; d.Code != "fieldorder" || d.Range.Start.Line != 10 || d.Range.Start.Character != 23 {
+		t.Errorf("diagnostics[1] = %+v", d)
+	}
+
+	// Unsaved changes withdraw the diagnostics and their fixes until they're undone.
+	change := func(text string) {
+		c.send("textDocument/didChange", 0, map[string]any{
+			"textDocument":   map[string]any{"uri": uri, "version": 2},
+			"contentChanges": []any{map[string]any{"text": text}},
+		})
+	}
+	change("\n" + string(src))
+	if got := c.diagnostics(uri); len(got) != 0 {
+		t.Errorf("got %d diagnostics for modified file, want 0: %+v", len(got), got)
+	}
+	if actions := c.codeActions(uri, diags[1].Range); len(actions) != 0 {
+		t.Errorf("got code actions %+v for modified file, want none", actions)
+	}
+	change(string(src))
+	if got := c.diagnostics(uri); len(got) != 2 {
+		t.Errorf("got %d diagnostics after undoing changes, want 2: %+v", len(got), got)
+	}
+
+	actions := c.codeActions(uri, diags[1].Range)
+	if len(actions) != 1 || actions[0].Kind != "quickfix" {
+		t.Fatalf("got code actions %+v, want one quick fix", actions)
+	}
+	edits := actions[0].Edit.Changes[uri]
+	if len(edits) != 2 || edits[0].NewText != `Name: "John"` || edits[0].Range.Start.Character != 24 {
+		t.Errorf("edits = %+v", edits)
+	}
+
+	var unknown struct {
+		Error *struct{ Code int }
+	}
+	c.ids++
+	c.send("workspace/unknown", c.ids, map[string]any{})
+	c.read(&unknown)
+	if unknown.Error == nil || unknown.Error.Code != -32601 {
+		t.Errorf("unknown method error = %+v, want code -32601", unknown.Error)
+	}
+
+	c.call("shutdown", nil, nil)
+	c.send("exit", 0, nil)
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestServeShutdown(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	blocking := &analysis.Analyzer{
+		Name: "blocking",
+		Doc:  "blocks until released",
+		Run: func(*analysis.Pass) (any, error) {
+			close(started)
+			<-release
+			return nil, nil
+		},
+	}
+
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- lsp.Serve(context.Background(), inR, outW, blocking)
+		outW.Close()
+	}()
+
+	c := &client{t: t, w: inW, r: textproto.NewReader(bufio.NewReader(outR))}
+	c.call("initialize", map[string]any{"processId": nil, "rootUri": nil, "capabilities": map[string]any{}}, nil)
+
+	path, err := filepath.Abs(filepath.Join("testdata", "a", "a.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	uri := (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+	c.send("textDocument/didOpen", 0, map[string]any{"textDocument": map[string]any{"uri": uri, "text": ""}})
+	<-started
+
+	// Requests are answered while the package is analyzed.
+	c.call("shutdown", nil, nil)
+	c.send("exit", 0, nil)
+	close(release)
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	// The canceled analysis publishes nothing.
+	if rest, err := io.ReadAll(c.r.R); err != nil || len(rest) > 0 {
+		t.Errorf("got output %q after shutdown (err %v), want none", rest, err)
+	}
+}
@@ -0,0 +1,11 @@
+package a
+
+type Person struct {
+	Name string
+	Age  int
+}
+
+// Adult has no test.
+func Adult(p Person) bool { return p.Age >= 18 }
+
+var _, _ = "👋", Person{Age: 30, Name: "John"}
@@ -0,0 +1,6 @@
+package a
+
+// clean is used, so it has no diagnostics.
+func clean() {}
+
+var _ = clean
@@ -0,0 +1,389 @@
+// Package refgraph builds graphs of the references between the functions,
+// methods and variables of a package, shared by analyzers reasoning about
+// reachability.
+package refgraph
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strconv"
+)
+
+// Graph is a directed graph of references between functions and methods,
+// keyed by their names qualified by the package path (e.g. "pkg.Type.Method"
+// or "pkg.Function"), so functions of the same name declared in a package and
+// in its external tests are distinct nodes.
+//
+// Function literals declared within functions are nodes of their own, keyed by
+// the enclosing function's key followed by "$" and their index (e.g.
+// "Function$1"). A closure is only referenced by the function invoking it,
+// passing it on or using the variable it is assigned to, so the calls it makes
+// are not attributed to its enclosing function otherwise.
+//
+// Initialized package-level variables are nodes too, keyed by their qualified
+// name, so functions referenced through aliases such as
+// "var ParseInternal = parse" are only reachable if the variable is used.
+type Graph struct {
+	// Decls maps keys to the declarations of the functions in the graph's files.
+	Decls map[string]*ast.FuncDecl
+
+	// Vars maps keys to the declarations of the initialized package-level
+	// variables in the graph's files.
+	Vars map[string]*ast.ValueSpec
+
+	edges    map[string][]string
+	closures map[string]bool
+	fileRefs map[*ast.File][]string
+}
+
+// New returns an empty graph.
+func New() *Graph {
+	return &Graph{
+		Decls:    make(map[string]*ast.FuncDecl),
+		Vars:     make(map[string]*ast.ValueSpec),
+		edges:    make(map[string][]string),
+		closures: make(map[string]bool),
+		fileRefs: make(map[*ast.File][]string),
+	}
+}
+
+// Add adds the references made in the given files to the graph. Functions and
+// variables referenced are only included if they match the filter. Adding the
+// files of several packages, such as a package and its external tests, merges
+// their nodes by key.
+func (g *Graph) Add(files []*ast.File, info *types.Info, filter func(types.Object) bool) {
+	newBuilder := func() *builder {
+		return &builder{
+			graph:   g,
+			info:    info,
+			filter:  filter,
+			lits:    make(map[*ast.FuncLit]string),
+			stored:  make(map[*ast.FuncLit]bool),
+			varLits: make(map[types.Object][]*ast.FuncLit),
+			targets: make(map[*ast.Ident]bool),
+			blanked: make(map[*ast.Ident]bool),
+		}
+	}
+
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				fn, ok := info.Defs[decl.Name].(*types.Func)
+				if !ok {
+					continue
+				}
+
+				key := Key(fn)
+				g.Decls[key] = decl
+
+				b := newBuilder()
+				b.scan(decl, key)
+				b.walk(decl, key)
+			case *ast.GenDecl:
+				if decl.Tok != token.VAR {
+					g.fileRefs[file] = append(g.fileRefs[file], references(decl, info, filter)...)
+					continue
+				}
+
+				for _, spec := range decl.Specs {
+					spec := spec.(*ast.ValueSpec)
+					for i, name := range spec.Names {
+						values := spec.Values
+						if len(spec.Names) == len(spec.Values) {
+							values = values[i : i+1]
+						}
+
+						// Variables which can't be referenced are always initialized.
+						if name.Name == "_" {
+							for _, value := range values {
+								g.fileRefs[file] = append(g.fileRefs[file], references(value, info, filter)...)
+							}
+							continue
+						}
+						if len(values) == 0 {
+							continue
+						}
+
+						obj := info.Defs[name]
+						if obj == nil {
+							continue
+						}
+
+						key := varKey(obj)
+						g.Vars[key] = spec
+						for _, value := range values {
+							b := newBuilder()
+							b.scan(value, key)
+							b.walk(value, key)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+// references returns the keys of the matching functions and variables
+// referenced within the node.
+func references(node ast.Node, info *types.Info, filter func(types.Object) bool) []string {
+	var refs []string
+
+	ast.Inspect(node, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok {
+			if key, ok := reference(info.Uses[ident], filter); ok {
+				refs = append(refs, key)
+			}
+		}
+		return true
+	})
+
+	return refs
+}
+
+// reference returns the key of a used object if it is a function or
+// package-level variable matching the filter.
+func reference(obj types.Object, filter func(types.Object) bool) (string, bool) {
+	switch obj := obj.(type) {
+	case *types.Func:
+		if filter(obj) {
+			return Key(obj.Origin()), true
+		}
+	case *types.Var:
+		if obj.Pkg() != nil && obj.Parent() == obj.Pkg().Scope() && filter(obj) {
+			return varKey(obj), true
+		}
+	}
+
+	return "", false
+}
+
+// builder adds the edges of a function declaration and its closures to a graph.
+type builder struct {
+	graph  *Graph
+	info   *types.Info
+	filter func(types.Object) bool
+
+	lits    map[*ast.FuncLit]string         // closure keys
+	stored  map[*ast.FuncLit]bool           // closures assigned to local variables
+	varLits map[types.Object][]*ast.FuncLit // closures assigned to each local variable
+	targets map[*ast.Ident]bool             // variables closures are assigned to
+	blanked map[*ast.Ident]bool             // variables assigned to the blank identifier
+}
+
+// scan assigns keys to the closures within node and records the variables
+// they are assigned to, so uses of a variable preceding its assignment, as in
+// recursive closures, are attributed too.
+func (b *builder) scan(node ast.Node, parent string) {
+	var n int
+
+	ast.Inspect(node, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.FuncLit:
+			n++
+			key := parent + "$" + strconv.Itoa(n)
+			b.lits[node] = key
+			b.graph.closures[key] = true
+			b.scan(node.Body, key)
+			return false
+		case *ast.AssignStmt:
+			if len(node.Lhs) == len(node.Rhs) {
+				for i := range node.Lhs {
+					b.store(node.Lhs[i], node.Rhs[i])
+				}
+			}
+		case *ast.ValueSpec:
+			if len(node.Names) == len(node.Values) {
+				for i := range node.Names {
+					b.store(node.Names[i], node.Values[i])
+				}
+			}
+		}
+		return true
+	})
+}
+
+// store records a closure assigned to a local variable. Variables assigned to
+// the blank identifier are recorded too, as this only silences unused variable
+// errors and doesn't invoke or pass on the closure.
+func (b *builder) store(lhs, rhs ast.Expr) {
+	ident, ok := ast.Unparen(lhs).(*ast.Ident)
+	if !ok {
+		return
+	}
+	if rhs, ok := ast.Unparen(rhs).(*ast.Ident); ok && ident.Name == "_" {
+		b.blanked[rhs] = true
+		return
+	}
+	lit, ok := ast.Unparen(rhs).(*ast.FuncLit)
+	if !ok {
+		return
+	}
+
+	b.stored[lit] = true
+	b.targets[ident] = true
+	if obj, ok := b.info.ObjectOf(ident).(*types.Var); ok {
+		b.varLits[obj] = append(b.varLits[obj], lit)
+	}
+}
+
+// walk adds edges from the function or closure key to the functions and
+// closures referenced within node.
+func (b *builder) walk(node ast.Node, key string) {
+	g := b.graph
+
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			// Closures which aren't assigned to a variable are invoked or passed on.
+			if !b.stored[n] {
+				g.edges[key] = append(g.edges[key], b.lits[n])
+			}
+			b.walk(n.Body, b.lits[n])
+			return false
+		case *ast.Ident:
+			if b.targets[n] {
+				return true
+			}
+			obj := b.info.Uses[n]
+			if ref, ok := reference(obj, b.filter); ok {
+				g.edges[key] = append(g.edges[key], ref)
+			}
+			if b.blanked[n] {
+				return true
+			}
+			for _, lit := range b.varLits[obj] {
+				g.edges[key] = append(g.edges[key], b.lits[lit])
+			}
+		}
+		return true
+	})
+}
+
+// Callees returns the keys of the functions referenced by the given function,
+// including those referenced by the closures it invokes or passes on.
+func (g *Graph) Callees(key string) []string {
+	var callees []string
+
+	seen := map[string]bool{key: true}
+	queue := []string{key}
+	for len(queue) > 0 {
+		key := queue[0]
+		queue = queue[1:]
+
+		for _, callee := range g.edges[key] {
+			if seen[callee] {
+				continue
+			}
+			seen[callee] = true
+			if g.closures[callee] {
+				queue = append(queue, callee)
+			} else {
+				callees = append(callees, callee)
+			}
+		}
+	}
+
+	return callees
+}
+
+// FileReferences returns the keys of the functions referenced outside of any
+// function in the given file, e.g. in package-level variable initializers.
+func (g *Graph) FileReferences(file *ast.File) []string {
+	return g.fileRefs[file]
+}
+
+// Reachable returns the set of functions reachable from the given roots,
+// including the roots themselves.
+func (g *Graph) Reachable(roots []string) map[string]bool {
+	reachable := make(map[string]bool, len(roots))
+	queue := make([]string, 0, len(roots))
+
+	for _, root := range roots {
+		if !reachable[root] {
+			reachable[root] = true
+			queue = append(queue, root)
+		}
+	}
+
+	for len(queue) > 0 {
+		key := queue[0]
+		queue = queue[1:]
+
+		for _, callee := range g.edges[key] {
+			if !reachable[callee] {
+				reachable[callee] = true
+				queue = append(queue, callee)
+			}
+		}
+	}
+
+	return reachable
+}
+
+// Distances returns the number of references on the shortest path from any of
+// the given roots to each reachable function or variable, which is zero for
+// the roots themselves. Closures are transparent, so calls made by a closure
+// are at the same distance as calls made by its enclosing function.
+func (g *Graph) Distances(roots []string) map[string]int {
+	distances := make(map[string]int, len(roots))
+	queue := make([]string, 0, len(roots))
+
+	for _, root := range roots {
+		if _, ok := distances[root]; !ok {
+			distances[root] = 0
+			queue = append(queue, root)
+		}
+	}
+
+	for len(queue) > 0 {
+		key := queue[0]
+		queue = queue[1:]
+
+		for _, callee := range g.Callees(key) {
+			if _, ok := distances[callee]; !ok {
+				distances[callee] = distances[key] + 1
+				queue = append(queue, callee)
+			}
+		}
+	}
+
+	return distances
+}
+
+// Key returns the name of a function qualified by its package path, including
+// the receiver type for methods (e.g., "pkg.Type.Method" or "pkg.Function").
+func Key(fn *types.Func) string {
+	if fn.Pkg() == nil {
+		return Name(fn)
+	}
+	return fn.Pkg().Path() + "." + Name(fn)
+}
+
+// Name returns the name of a function, including the receiver type for
+// methods (e.g., "Type.Method" or "Function").
+func Name(fn *types.Func) string {
+	sig := fn.Type().(*types.Signature)
+
+	recv := sig.Recv()
+	if recv == nil {
+		return fn.Name()
+	}
+
+	recvType := recv.Type()
+	if ptr, ok := recvType.(*types.Pointer); ok {
+		recvType = ptr.Elem()
+	}
+
+	if named, ok := recvType.(*types.Named); ok {
+		return named.Obj().Name() + "." + fn.Name()
+	}
+
+	return recvType.String() + "." + fn.Name()
+}
+
+// varKey returns the key of a package-level variable.
+func varKey(obj types.Object) string {
+	return obj.Pkg().Path() + "." + obj.Name()
+}
@@ -0,0 +1,138 @@
+package refgraph_test
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/abemedia/gocheck/internal/refgraph"
+)
+
+const src = `package test
+
+var handler = onInit
+
+var unused = leaf
+
+var _ = orphan
+
+type T struct{}
+
+func (T) Method() { helper(); _ = handler }
+
+func (*T) ptr() {}
+
+func helper() { leaf() }
+
+func leaf() {}
+
+func onInit() {}
+
+func orphan() { leaf() }
+
+func closures() {
+	func() { leaf() }()
+	stored := func() { orphan() }
+	_ = stored
+}
+`
+
+func TestReachable(t *testing.T) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "test.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info := &types.Info{
+		Defs: make(map[*ast.Ident]types.Object),
+		Uses: make(map[*ast.Ident]types.Object),
+	}
+	conf := types.Config{Importer: importer.Default()}
+	pkg, err := conf.Check("test", fset, []*ast.File{file}, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	graph := refgraph.New()
+	graph.Add([]*ast.File{file}, info, func(obj types.Object) bool {
+		return obj.Pkg() == pkg
+	})
+
+	if got, want := slices.Sorted(maps.Keys(graph.Decls)), []string{"test.T.Method", "test.T.ptr", "test.closures", "test.helper", "test.leaf", "test.onInit", "test.orphan"}; !slices.Equal(got, want) {
+		t.Errorf("Decls = %v, want %v", got, want)
+	}
+	if got, want := slices.Sorted(maps.Keys(graph.Vars)), []string{"test.handler", "test.unused"}; !slices.Equal(got, want) {
+		t.Errorf("Vars = %v, want %v", got, want)
+	}
+	if got, want := graph.FileReferences(file), []string{"test.orphan"}; !slices.Equal(got, want) {
+		t.Errorf("FileReferences = %v, want %v", got, want)
+	}
+	if got, want := graph.Callees("test.T.Method"), []string{"test.helper", "test.handler"}; !slices.Equal(got, want) {
+		t.Errorf("Callees = %v, want %v", got, want)
+	}
+
+	if got, want := graph.Callees("test.closures"), []string{"test.leaf"}; !slices.Equal(got, want) {
+		t.Errorf("Callees = %v, want %v", got, want)
+	}
+
+	got := slices.Sorted(maps.Keys(graph.Reachable([]string{"test.T.Method"})))
+	want := []string{"test.T.Method", "test.handler", "test.helper", "test.leaf", "test.onInit"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Reachable = %v, want %v", got, want)
+	}
+
+	// Closures are transparent, so leaf is a call away from closures.
+	distances := graph.Distances([]string{"test.T.Method", "test.closures"})
+	wantDistances := map[string]int{
+		"test.T.Method": 0,
+		"test.closures": 0,
+		"test.helper":   1,
+		"test.handler":  1,
+		"test.leaf":     1,
+		"test.onInit":   2,
+	}
+	if !maps.Equal(distances, wantDistances) {
+		t.Errorf("Distances = %v, want %v", distances, wantDistances)
+	}
+}
+
+func TestReachablePackages(t *testing.T) {
+	fset := token.NewFileSet()
+	info := &types.Info{
+		Defs: make(map[*ast.Ident]types.Object),
+		Uses: make(map[*ast.Ident]types.Object),
+	}
+	check := func(path, src string) (*types.Package, *ast.File) {
+		t.Helper()
+		file, err := parser.ParseFile(fset, path+".go", src, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pkg, err := (&types.Config{}).Check(path, fset, []*ast.File{file}, info)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return pkg, file
+	}
+
+	// The same names declared in two packages, such as a package and its
+	// external tests, are distinct nodes.
+	pkg, file := check("p", "package p\n\nvar hook = Hidden\n\nfunc Hidden() {}\n\nfunc setup() { Hidden() }\n")
+	testPkg, testFile := check("p_test", "package p_test\n\nvar hook = func() {}\n\nfunc setup() {}\n\nfunc Test() { setup(); hook() }\n")
+
+	graph := refgraph.New()
+	graph.Add([]*ast.File{file}, info, func(obj types.Object) bool { return obj.Pkg() == pkg })
+	graph.Add([]*ast.File{testFile}, info, func(obj types.Object) bool { return obj.Pkg() == testPkg })
+
+	got := slices.Sorted(maps.Keys(graph.Reachable([]string{"p_test.Test"})))
+	want := []string{"p_test.Test", "p_test.hook", "p_test.hook$1", "p_test.setup"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Reachable = %v, want %v", got, want)
+	}
+}
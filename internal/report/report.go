@@ -0,0 +1,210 @@
+// Package report implements the untested-report command, which renders the
+// results of the untested analyzer for a set of packages as a single document.
+// As with the analyzer, only exported functions and methods are covered, not
+// types, variables or constants.
+package report
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/checker"
+	"golang.org/x/tools/go/packages"
+
+	"github.com/abemedia/gocheck/untested"
+)
+
+// Report lists the exported functions and methods of each package along with
+// their test coverage.
+type Report struct {
+	Packages []*untested.Result `json:"packages"`
+	Total    untested.Summary   `json:"total"`
+}
+
+// Run runs the untested-report command with the given arguments, writing the
+// report to stdout unless an output file is given.
+func Run(args []string, stdout io.Writer) error {
+	analyzer := untested.NewAnalyzer()
+
+	fs := flag.NewFlagSet("untested-report", flag.ContinueOnError)
+	format := fs.String("format", "markdown", "output format: markdown, html or json")
+	output := fs.String("o", "", "write the report to the given file instead of stdout")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: gocheck untested-report [flags] [packages]")
+		fmt.Fprintln(fs.Output(), "\nLists the exported functions and methods of the packages with their test coverage.")
+		fs.PrintDefaults()
+	}
+
+	// The untested analyzer's flags are accepted unprefixed.
+	analyzer.Flags.VisitAll(func(f *flag.Flag) {
+		fs.Var(f.Value, f.Name, f.Usage)
+	})
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	patterns := fs.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"."}
+	}
+
+	rep, err := analyze(analyzer, patterns)
+	if err != nil {
+		return err
+	}
+
+	if *output == "" {
+		return Render(stdout, *format, rep)
+	}
+
+	f, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+
+	if err := Render(f, *format, rep); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// analyze runs the untested analyzer on the packages matching the patterns.
+func analyze(analyzer *analysis.Analyzer, patterns []string) (*Report, error) {
+	pkgs, err := packages.Load(&packages.Config{Mode: packages.LoadSyntax}, patterns...)
+	if err != nil {
+		return nil, err
+	}
+	if n := packages.PrintErrors(pkgs); n > 0 {
+		return nil, errors.New("failed to load packages")
+	}
+
+	graph, err := checker.Analyze([]*analysis.Analyzer{analyzer}, pkgs, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	wd, _ := os.Getwd()
+	rep := &Report{}
+
+	for _, act := range graph.Roots {
+		if act.Err != nil {
+			return nil, fmt.Errorf("%s: %w", act.Package.PkgPath, act.Err)
+		}
+
+		result := act.Result.(*untested.Result)
+		if len(result.Functions) == 0 && result.Error == "" {
+			continue
+		}
+
+		for _, fn := range result.Functions {
+			fn.Position = relative(wd, fn.Position)
+		}
+		rep.Packages = append(rep.Packages, result)
+	}
+
+	slices.SortFunc(rep.Packages, func(a, b *untested.Result) int { return strings.Compare(a.Package, b.Package) })
+	rep.Total = total(rep.Packages)
+
+	return rep, nil
+}
+
+// relative returns the position with its filename relative to dir if possible.
+func relative(dir, pos string) string {
+	if rel, err := filepath.Rel(dir, pos); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+
+	return pos
+}
+
+// total returns the number of tested functions across the packages. Ignored
+// functions don't count towards the total.
+func total(results []*untested.Result) untested.Summary {
+	var summary untested.Summary
+	for _, result := range results {
+		s := result.Summary()
+		summary.Tested += s.Tested
+		summary.Total += s.Total
+	}
+	summary.Ratio = untested.Ratio(summary.Tested, summary.Total)
+
+	return summary
+}
+
+// Render writes the report in the given format: markdown, html or json.
+func Render(w io.Writer, format string, rep *Report) error {
+	switch format {
+	case "markdown", "md":
+		return markdownTemplate.Execute(w, rep)
+	case "html":
+		return htmlTemplate.Execute(w, rep)
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rep)
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+}
+
+var funcs = map[string]any{
+	"summarize": (*untested.Result).Summary,
+	"percent":   func(ratio float64) string { return fmt.Sprintf("%.1f%%", ratio*100) },
+	"join":      strings.Join,
+}
+
+var markdownTemplate = template.Must(template.New("markdown").Funcs(funcs).Parse(`# Untested API report
+
+{{with .Total}}{{.Tested}} of {{.Total}} exported functions and methods are tested ({{percent .Ratio}}).{{end}}
+{{range .Packages}}
+## {{.Package}}
+{{if .Error}}
+> {{.Error}}
+{{else}}{{with summarize .}}
+{{.Tested}} of {{.Total}} tested ({{percent .Ratio}}).
+{{end}}
+| Name | Kind | Status | Depth | Tests | Position |
+| ---- | ---- | ------ | ----- | ----- | -------- |
+{{range .Functions}}| ` + "`{{.Name}}`" + ` | {{.Kind}} | {{if .Tested}}✅ tested{{else if .Ignored}}➖ ignored: {{.Ignored}}{{else}}❌ untested{{end}} | {{if .Depth}}{{.Depth}}{{end}} | {{join .Tests ", "}} | {{.Position}} |
+{{end}}{{end}}{{end}}`))
+
+var htmlTemplate = htmltemplate.Must(htmltemplate.New("html").Funcs(funcs).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Untested API report</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 0.25em 0.5em; text-align: left; }
+.tested { color: #1a7f37; }
+.untested { color: #cf222e; }
+.ignored { color: #6e7781; }
+</style>
+</head>
+<body>
+<h1>Untested API report</h1>
+{{with .Total}}<p>{{.Tested}} of {{.Total}} exported functions and methods are tested ({{percent .Ratio}}).</p>{{end}}
+{{range .Packages}}<h2>{{.Package}}</h2>
+{{if .Error}}<p class="untested">{{.Error}}</p>
+{{else}}{{with summarize .}}<p>{{.Tested}} of {{.Total}} tested ({{percent .Ratio}}).</p>{{end}}
+<table>
+<tr><th>Name</th><th>Kind</th><th>Status</th><th>Depth</th><th>Tests</th><th>Position</th></tr>
+{{range .Functions}}<tr><td><code>{{.Name}}</code></td><td>{{.Kind}}</td>{{if .Tested}}<td class="tested">tested</td>{{else if .Ignored}}<td class="ignored">ignored: {{.Ignored}}</td>{{else}}<td class="untested">untested</td>{{end}}<td>{{if .Depth}}{{.Depth}}{{end}}</td><td>{{join .Tests ", "}}</td><td>{{.Position}}</td></tr>
+{{end}}</table>
+{{end}}{{end}}</body>
+</html>
+`))
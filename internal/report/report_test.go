@@ -0,0 +1,95 @@
+package report_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/abemedia/gocheck/internal/report"
+	"github.com/abemedia/gocheck/untested"
+)
+
+func TestRender(t *testing.T) {
+	rep := &report.Report{
+		Packages: []*untested.Result{
+			{
+				Package: "example.com/parser",
+				Functions: []*untested.Function{
+					{Name: "Parse", Kind: "function", Position: "parser/parser.go:10:1", Tested: true, Tests: []string{"TestParse", "TestRoundTrip"}, Depth: 1},
+					{Name: "Parser.Reset", Kind: "method", Position: "parser/parser.go:20:1", Tested: true, Tests: []string{"TestParse"}, Depth: 2},
+					{Name: "Format", Kind: "function", Position: "parser/format.go:5:1"},
+					{Name: "Debug", Kind: "function", Position: "parser/debug.go:3:1", Ignored: "debugging aid"},
+				},
+			},
+			{
+				Package: "example.com/broken",
+				Error:   "could not analyze tests: broken_test.go:3:1: expected declaration",
+			},
+		},
+		Total: untested.Summary{Tested: 2, Total: 3, Ratio: 2.0 / 3},
+	}
+
+	tests := []struct {
+		format string
+		golden string
+	}{
+		{format: "markdown", golden: "report.md"},
+		{format: "html", golden: "report.html"},
+		{format: "json", golden: "report.json"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := report.Render(&buf, test.format, rep); err != nil {
+				t.Fatal(err)
+			}
+
+			want, err := os.ReadFile(filepath.Join("testdata", test.golden))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := buf.String(); got != string(want) {
+				t.Errorf("got:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+
+	if err := report.Render(&bytes.Buffer{}, "xml", rep); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
+
+func TestRun(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "report.json")
+	if err := report.Run([]string{"-internal", "-format=json", "-o", output, "./testdata/example"}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile(filepath.Join("testdata", "example.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	// Internal packages are skipped unless requested.
+	var buf bytes.Buffer
+	if err := report.Run([]string{"-format=json", "./testdata/example"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if want := "{\n  \"packages\": null,\n  \"total\": {\n    \"tested\": 0,\n    \"total\": 0,\n    \"ratio\": 1\n  }\n}\n"; buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	if err := report.Run([]string{"./testdata/missing"}, &buf); err == nil {
+		t.Error("expected error for missing package")
+	}
+}
@@ -0,0 +1,15 @@
+package example
+
+// Parse is tested directly.
+func Parse(s string) string { return Format(s) }
+
+// Format is tested through Parse.
+func Format(s string) string { return s }
+
+// Debug is ignored.
+//
+//untested:ignore debugging aid
+func Debug() {}
+
+// Untested has no test.
+func Untested() {}
@@ -0,0 +1,9 @@
+package example
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	if Parse("x") != "x" {
+		t.Fail()
+	}
+}
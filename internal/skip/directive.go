@@ -0,0 +1,271 @@
+package skip
+
+import (
+	"cmp"
+	"go/ast"
+	"go/token"
+	"slices"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// Directives holds the directives of a pass suppressing its analyzer:
+//
+//	//nolint
+//	//nolint:fieldorder,untested
+//	//gocheck:ignore fieldorder reason
+//
+// A directive preceding the package clause applies to the whole file. A
+// directive at the end of a line applies to the outermost node starting on
+// that line, or to the rest of the line if there is none, and a directive on
+// its own line applies to the outermost node starting on the following line.
+// A plain //nolint, or the name "all", suppresses every analyzer.
+//
+// Like //untested:ignore, //gocheck:ignore directives must give a reason and
+// are reported if they don't suppress anything. //nolint directives follow
+// golangci-lint, which leaves these checks to its nolintlint linter.
+type Directives struct {
+	pass       *analysis.Pass
+	directives []*directive
+	files      []fileDirectives // sorted by position
+}
+
+// fileDirectives holds the directives of a file, grouped into sorted ranges
+// which don't overlap, so they can be binary searched.
+type fileDirectives struct {
+	Range
+	groups []directiveGroup
+}
+
+// directiveGroup is a range covered by overlapping directives.
+type directiveGroup struct {
+	Range
+	directives []*directive
+}
+
+// directive is a directive suppressing the analyzer within a range.
+type directive struct {
+	Range
+	comment *ast.Comment
+	ignore  bool   // whether it is a //gocheck:ignore directive
+	only    bool   // whether it names the analyzer only
+	reason  string // reason given by a //gocheck:ignore directive
+	used    bool
+}
+
+// NewDirectives parses the directives suppressing the pass's analyzer.
+func NewDirectives(pass *analysis.Pass) *Directives {
+	d := &Directives{pass: pass}
+
+	for _, file := range pass.Files {
+		var index *lineIndex
+		var directives []*directive
+
+		for _, cg := range file.Comments {
+			for _, c := range cg.List {
+				dir, ok := parseDirective(c, pass.Analyzer.Name)
+				if !ok {
+					continue
+				}
+
+				if cg.End() < file.Package {
+					dir.Range = Range{Start: file.Pos(), End: file.End()}
+					directives = append(directives, dir)
+					continue
+				}
+
+				if index == nil {
+					index = newLineIndex(pass.Fset.File(file.Pos()), file)
+				}
+				if r, ok := index.rangeOf(c, cg); ok {
+					dir.Range = r
+					directives = append(directives, dir)
+				}
+			}
+		}
+
+		if len(directives) > 0 {
+			d.directives = append(d.directives, directives...)
+			d.files = append(d.files, fileDirectives{
+				Range:  Range{Start: file.Pos(), End: file.End()},
+				groups: groupDirectives(directives),
+			})
+		}
+	}
+
+	slices.SortFunc(d.files, func(a, b fileDirectives) int { return cmp.Compare(a.Start, b.Start) })
+
+	return d
+}
+
+// groupDirectives sorts the directives by position and groups overlapping
+// ones, as with the ranges of [NewRangeStrategy].
+func groupDirectives(directives []*directive) []directiveGroup {
+	sorted := slices.Clone(directives)
+	slices.SortStableFunc(sorted, func(a, b *directive) int { return cmp.Compare(a.Start, b.Start) })
+
+	var groups []directiveGroup
+	for _, dir := range sorted {
+		if n := len(groups); n > 0 && dir.Start <= groups[n-1].End {
+			groups[n-1].End = max(groups[n-1].End, dir.End)
+			groups[n-1].directives = append(groups[n-1].directives, dir)
+			continue
+		}
+		groups = append(groups, directiveGroup{Range: dir.Range, directives: []*directive{dir}})
+	}
+
+	return groups
+}
+
+// Suppressed determines if a diagnostic reported at the node is suppressed,
+// returning the reason of the directive, or its text if it has none, and
+// marking the directives suppressing it as used. Analyzers should only call it
+// for nodes they would otherwise report, so unused directives can be told
+// apart.
+func (d *Directives) Suppressed(node ast.Node) (string, bool) {
+	pos := node.Pos()
+
+	i := sort.Search(len(d.files), func(i int) bool { return d.files[i].End >= pos })
+	if i == len(d.files) || d.files[i].Start > pos {
+		return "", false
+	}
+	groups := d.files[i].groups
+
+	j := sort.Search(len(groups), func(j int) bool { return groups[j].End >= pos })
+	if j == len(groups) || groups[j].Start > pos {
+		return "", false
+	}
+
+	var reason string
+	suppressed := false
+	for _, dir := range groups[j].directives {
+		if dir.Start <= pos && pos <= dir.End {
+			if !suppressed {
+				reason = cmp.Or(dir.reason, strings.TrimPrefix(dir.comment.Text, "//"))
+			}
+			dir.used = true
+			suppressed = true
+		}
+	}
+
+	return reason, suppressed
+}
+
+// Report reports the //gocheck:ignore directives naming the analyzer without
+// a reason, and those naming only the analyzer which did not suppress any
+// diagnostic, as directives naming several analyzers may be used by the
+// others. Only directives matching the filter are reported, or all of them if
+// it is nil.
+func (d *Directives) Report(filter NodeFilter) {
+	for _, dir := range d.directives {
+		if !dir.ignore || (filter != nil && !filter(dir.comment)) {
+			continue
+		}
+		if dir.reason == "" {
+			d.pass.ReportRangef(dir.comment, "//gocheck:ignore directive requires a reason")
+		}
+		if dir.only && !dir.used {
+			d.pass.ReportRangef(dir.comment, "//gocheck:ignore directive does not suppress anything")
+		}
+	}
+}
+
+// parseDirective parses a //nolint or //gocheck:ignore directive suppressing
+// the named analyzer.
+func parseDirective(c *ast.Comment, analyzer string) (*directive, bool) {
+	dir := &directive{comment: c}
+
+	var names string
+	if rest, ok := strings.CutPrefix(c.Text, "//nolint"); ok {
+		if rest == "" || rest[0] == ' ' || rest[0] == '\t' {
+			return dir, true
+		}
+		if rest[0] != ':' {
+			return nil, false
+		}
+		names, _, _ = strings.Cut(rest[1:], " ")
+	} else if rest, ok := strings.CutPrefix(c.Text, "//gocheck:ignore"); ok {
+		fields := strings.Fields(rest)
+		if len(fields) == 0 || (rest[0] != ' ' && rest[0] != '\t') {
+			return nil, false
+		}
+		names = fields[0]
+		dir.ignore = true
+		dir.reason = strings.Join(fields[1:], " ")
+	} else {
+		return nil, false
+	}
+
+	matched := false
+	dir.only = true
+	for name := range strings.SplitSeq(names, ",") {
+		switch name = strings.TrimSpace(name); name {
+		case analyzer:
+			matched = true
+		case "all":
+			matched = true
+			dir.only = false
+		default:
+			dir.only = false
+		}
+	}
+
+	return dir, matched
+}
+
+// lineIndex indexes the nodes of a file by line.
+type lineIndex struct {
+	file   *token.File
+	starts map[int]ast.Node  // outermost node starting on each line
+	first  map[int]token.Pos // first position of a node starting or ending on each line
+}
+
+// newLineIndex indexes the nodes of the file by line.
+func newLineIndex(tf *token.File, file *ast.File) *lineIndex {
+	idx := &lineIndex{file: tf, starts: make(map[int]ast.Node), first: make(map[int]token.Pos)}
+
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch n.(type) {
+		case nil, *ast.File, *ast.CommentGroup, *ast.Comment:
+			return n != nil
+		}
+
+		line := tf.Line(n.Pos())
+		if _, ok := idx.starts[line]; !ok {
+			idx.starts[line] = n
+		}
+		for _, pos := range []token.Pos{n.Pos(), n.End() - 1} {
+			line := tf.Line(pos)
+			if first, ok := idx.first[line]; !ok || pos < first {
+				idx.first[line] = pos
+			}
+		}
+
+		return true
+	})
+
+	return idx
+}
+
+// rangeOf returns the range suppressed by the directive comment c in the
+// comment group cg.
+func (idx *lineIndex) rangeOf(c *ast.Comment, cg *ast.CommentGroup) (Range, bool) {
+	line := idx.file.Line(c.Pos())
+
+	// Directives at the end of a line.
+	if first, ok := idx.first[line]; ok && first < c.Pos() {
+		if node, ok := idx.starts[line]; ok && node.Pos() < c.Pos() {
+			return Range{Start: node.Pos(), End: node.End()}, true
+		}
+		return Range{Start: idx.file.LineStart(line), End: c.Pos()}, true
+	}
+
+	// Directives on their own line, e.g. in doc comments.
+	if node, ok := idx.starts[idx.file.Line(cg.End())+1]; ok {
+		return Range{Start: node.Pos(), End: node.End()}, true
+	}
+
+	return Range{}, false
+}
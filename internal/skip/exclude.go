@@ -0,0 +1,172 @@
+package skip
+
+import (
+	"fmt"
+	"go/ast"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// Exclude is a list of exclusion patterns, usable as a flag. Patterns are
+// comma-separated and may be repeated, each being one of:
+//
+//	**/mocks/**           glob matched against file paths
+//	*_gen.go              glob without a slash, matched against file names
+//	re:_string\.go$       regular expression matched against file paths
+//	pkg:example.com/x/... package path, where "..." matches any string
+//	marker:@generated     text marking files as generated before the package clause
+type Exclude struct {
+	patterns []string
+	paths    []*regexp.Regexp
+	pkgs     []*regexp.Regexp
+	markers  []string
+}
+
+// String returns the patterns as passed to Set.
+func (e *Exclude) String() string {
+	return strings.Join(e.patterns, ",")
+}
+
+// Set adds the comma-separated patterns.
+func (e *Exclude) Set(value string) error {
+	for pattern := range strings.SplitSeq(value, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern == "" {
+			continue
+		}
+
+		switch kind, rest, _ := strings.Cut(pattern, ":"); kind {
+		case "re":
+			re, err := regexp.Compile(rest)
+			if err != nil {
+				return fmt.Errorf("invalid exclude pattern %q: %w", pattern, err)
+			}
+			e.paths = append(e.paths, re)
+		case "pkg":
+			e.pkgs = append(e.pkgs, compilePackagePattern(rest))
+		case "marker":
+			e.markers = append(e.markers, rest)
+		default:
+			re, err := compileGlob(pattern)
+			if err != nil {
+				return fmt.Errorf("invalid exclude pattern %q: %w", pattern, err)
+			}
+			e.paths = append(e.paths, re)
+		}
+
+		e.patterns = append(e.patterns, pattern)
+	}
+
+	return nil
+}
+
+// NewExcludeStrategy creates a node filter that skips nodes from files matching
+// the exclusion patterns, or all nodes if the package matches.
+func NewExcludeStrategy(pass *analysis.Pass, exclude *Exclude) NodeFilter {
+	pkgExcluded := matchAny(exclude.pkgs, pass.Pkg.Path())
+
+	return NewFileStrategy(pass, func(file *ast.File) bool {
+		filename := filepath.ToSlash(pass.Fset.Position(file.Pos()).Filename)
+		return pkgExcluded || matchAny(exclude.paths, filename) || hasMarker(file, exclude.markers)
+	})
+}
+
+// compileGlob compiles a glob pattern to a regular expression matching file
+// paths.
+func compileGlob(pattern string) (*regexp.Regexp, error) {
+	if _, err := path.Match(strings.ReplaceAll(pattern, "**", "*"), ""); err != nil {
+		return nil, err
+	}
+
+	// Patterns match whole path elements at the end of the path unless rooted.
+	var b strings.Builder
+	if strings.HasPrefix(pattern, "/") {
+		b.WriteString("^")
+	} else {
+		b.WriteString("(?:^|/)")
+	}
+
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			if strings.HasPrefix(pattern[i:], "**/") {
+				b.WriteString("(?:.*/)?")
+				i += 2
+			} else if strings.HasPrefix(pattern[i:], "**") {
+				b.WriteString(".*")
+				i++
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(pattern[i:], ']')
+			if end < 0 {
+				return nil, path.ErrBadPattern
+			}
+			// Classes are negated by a leading "!", as in shells, or "^".
+			class := pattern[i+1 : i+end]
+			if rest, ok := strings.CutPrefix(class, "!"); ok {
+				class = "^" + rest
+			}
+			b.WriteString("[" + class + "]")
+			i += end
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+
+	return regexp.Compile(b.String())
+}
+
+// compilePackagePattern compiles a package path pattern to a regular
+// expression, where "..." matches any string and a trailing "/..." also
+// matches the package itself, as with the go command.
+func compilePackagePattern(pattern string) *regexp.Regexp {
+	re := regexp.QuoteMeta(pattern)
+	if strings.HasSuffix(re, `/\.\.\.`) {
+		re = strings.TrimSuffix(re, `/\.\.\.`) + `(?:/.*)?`
+	}
+	re = strings.ReplaceAll(re, `\.\.\.`, `.*`)
+
+	return regexp.MustCompile("^" + re + "$")
+}
+
+// matchAny determines if s matches any of the regular expressions.
+func matchAny(patterns []*regexp.Regexp, s string) bool {
+	for _, re := range patterns {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hasMarker determines if a comment before the file's package clause contains
+// any of the markers.
+func hasMarker(file *ast.File, markers []string) bool {
+	if len(markers) == 0 {
+		return false
+	}
+
+	for _, cg := range file.Comments {
+		if cg.Pos() > file.Package {
+			break
+		}
+		for _, c := range cg.List {
+			for _, marker := range markers {
+				if strings.Contains(c.Text, marker) {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
@@ -18,23 +18,86 @@ type (
 	NodeFilter func(ast.Node) bool
 )
 
+// Any returns a filter that skips what any of the given filters skip.
+func Any[F ~func(T) bool, T any](filters ...F) F {
+	return func(v T) bool {
+		for _, filter := range filters {
+			if filter(v) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// All returns a filter that skips what all of the given filters skip.
+func All[F ~func(T) bool, T any](filters ...F) F {
+	return func(v T) bool {
+		for _, filter := range filters {
+			if !filter(v) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Not returns a filter that skips what the given filter doesn't skip.
+func Not[F ~func(T) bool, T any](filter F) F {
+	return func(v T) bool { return !filter(v) }
+}
+
 // NewFileStrategy creates a node filter that skips nodes from files matching the given filter.
 func NewFileStrategy(pass *analysis.Pass, filter FileFilter) NodeFilter {
-	skip := make([]fileRange, 0, len(pass.Files))
+	skip := make([]Range, 0, len(pass.Files))
 	for _, file := range pass.Files {
 		if filter(file) {
-			skip = append(skip, fileRange{start: file.Pos(), end: file.End()})
+			skip = append(skip, Range{Start: file.Pos(), End: file.End()})
 		}
 	}
 
-	slices.SortFunc(skip, func(a, b fileRange) int { return cmp.Compare(a.start, b.start) })
+	return newRangeFilter(skip)
+}
+
+// Range is a range of positions, including both ends.
+type Range struct{ Start, End token.Pos }
+
+// NewRangeStrategy creates a node filter that skips nodes starting within any
+// of the given ranges, e.g. the hunks of a diff or the regions of a build
+// constraint. Lookups take logarithmic time in the number of ranges.
+func NewRangeStrategy(ranges ...Range) NodeFilter {
+	return newRangeFilter(slices.Clone(ranges))
+}
+
+// NewOverlapStrategy creates a node filter that skips nodes overlapping any
+// of the given ranges, rather than only those starting within one, e.g. the
+// functions containing a changed line of a diff. Lookups take logarithmic time
+// in the number of ranges.
+func NewOverlapStrategy(ranges ...Range) NodeFilter {
+	merged := mergeRanges(slices.Clone(ranges))
+
+	return func(node ast.Node) bool {
+		i, _ := slices.BinarySearchFunc(merged, node.Pos(), func(r Range, pos token.Pos) int {
+			if r.End < pos {
+				return -1
+			}
+			return +1
+		})
+		return i < len(merged) && merged[i].Start <= node.End()
+	}
+}
+
+// newRangeFilter creates a node filter that skips nodes starting within any of
+// the ranges. The ranges are sorted in place.
+func newRangeFilter(ranges []Range) NodeFilter {
+	merged := mergeRanges(ranges)
 
 	return func(node ast.Node) bool {
-		_, found := slices.BinarySearchFunc(skip, node.Pos(), func(r fileRange, pos token.Pos) int {
-			if pos < r.start {
+		_, found := slices.BinarySearchFunc(merged, node.Pos(), func(r Range, pos token.Pos) int {
+			if pos < r.Start {
 				return +1
 			}
-			if pos > r.end {
+			if pos > r.End {
 				return -1
 			}
 			return 0
@@ -43,4 +106,19 @@ func NewFileStrategy(pass *analysis.Pass, filter FileFilter) NodeFilter {
 	}
 }
 
-type fileRange struct{ start, end token.Pos }
+// mergeRanges sorts the ranges in place and merges overlapping ones, so they
+// can be binary searched.
+func mergeRanges(ranges []Range) []Range {
+	slices.SortFunc(ranges, func(a, b Range) int { return cmp.Compare(a.Start, b.Start) })
+
+	merged := ranges[:0]
+	for _, r := range ranges {
+		if n := len(merged); n > 0 && r.Start <= merged[n-1].End {
+			merged[n-1].End = max(merged[n-1].End, r.End)
+			continue
+		}
+		merged = append(merged, r)
+	}
+
+	return merged
+}
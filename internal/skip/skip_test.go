@@ -1,9 +1,12 @@
 package skip_test
 
 import (
+	"fmt"
 	"go/ast"
 	"go/parser"
 	"go/token"
+	"go/types"
+	"slices"
 	"testing"
 
 	"github.com/abemedia/gocheck/internal/skip"
@@ -55,3 +58,382 @@ func TestNewFileStrategy(t *testing.T) {
 		}
 	}
 }
+
+func TestDirectives(t *testing.T) {
+	const src = `package test
+
+//nolint:fieldorder
+type A struct{}
+
+type B struct{} //nolint:other
+
+// C has a directive in its doc comment.
+//
+//gocheck:ignore fieldorder,untested constructed by hand
+func C() {
+	x := 1
+	_ = x
+}
+
+func D() {
+	y := 1 //nolint
+	_ = y
+	//nolintfoo
+	_ = y
+	//gocheck:ignore
+	_ = y
+	//gocheck:ignore fieldorder
+	_ = y
+	//gocheck:ignore fieldorder,untested unused by fieldorder
+	_ = y
+}
+`
+
+	const fileSrc = `//nolint:all
+package test
+
+type E struct{}
+`
+
+	const nestedSrc = `package test
+
+type G struct{}
+
+//gocheck:ignore fieldorder outer
+func H() {
+	_ = 1 //gocheck:ignore fieldorder inner
+}
+`
+
+	pass := &analysis.Pass{Analyzer: &analysis.Analyzer{Name: "fieldorder"}, Fset: token.NewFileSet()}
+	for i, content := range []string{src, fileSrc, nestedSrc} {
+		file, err := parser.ParseFile(pass.Fset, fmt.Sprintf("file%d.go", i), content, parser.ParseComments)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pass.Files = append(pass.Files, file)
+	}
+
+	decls := pass.Files[0].Decls
+	body := decls[3].(*ast.FuncDecl).Body.List
+	tests := []struct {
+		name string
+		node ast.Node
+		skip bool
+	}{
+		{name: "standalone", node: decls[0], skip: true},
+		{name: "other analyzer", node: decls[1], skip: false},
+		{name: "doc comment", node: decls[2], skip: true},
+		{name: "doc comment body", node: decls[2].(*ast.FuncDecl).Body.List[0], skip: true},
+		{name: "unsuppressed", node: decls[3], skip: false},
+		{name: "end of line", node: body[0], skip: true},
+		{name: "following line", node: body[1], skip: false},
+		{name: "invalid nolint", node: body[2], skip: false},
+		{name: "ignore without analyzer", node: body[3], skip: false},
+		{name: "file", node: pass.Files[1].Decls[0], skip: true},
+		{name: "after file", node: pass.Files[2].Decls[0], skip: false},
+		{name: "nested", node: pass.Files[2].Decls[1].(*ast.FuncDecl).Body.List[0], skip: true},
+	}
+
+	var diagnostics []string
+	pass.Report = func(d analysis.Diagnostic) {
+		diagnostics = append(diagnostics, fmt.Sprintf("%s: %s", pass.Fset.Position(d.Pos), d.Message))
+	}
+
+	directives := skip.NewDirectives(pass)
+
+	for _, tt := range tests {
+		if _, got := directives.Suppressed(tt.node); got != tt.skip {
+			t.Errorf("Suppressed(%s) = %v, expected %v", tt.name, got, tt.skip)
+		}
+	}
+
+	if reason, _ := directives.Suppressed(decls[2]); reason != "constructed by hand" {
+		t.Errorf("Suppressed(doc comment) reason = %q, expected %q", reason, "constructed by hand")
+	}
+	if reason, _ := directives.Suppressed(decls[0]); reason != "nolint:fieldorder" {
+		t.Errorf("Suppressed(standalone) reason = %q, expected %q", reason, "nolint:fieldorder")
+	}
+
+	if reason, _ := directives.Suppressed(pass.Files[2].Decls[1].(*ast.FuncDecl).Body.List[0]); reason != "outer" {
+		t.Errorf("Suppressed(nested) reason = %q, expected %q", reason, "outer")
+	}
+
+	directives.Report(nil)
+
+	want := []string{
+		"file0.go:23:2: //gocheck:ignore directive requires a reason",
+		"file0.go:23:2: //gocheck:ignore directive does not suppress anything",
+	}
+	if !slices.Equal(diagnostics, want) {
+		t.Errorf("Report() reported %q, expected %q", diagnostics, want)
+	}
+}
+
+func TestNewExcludeStrategy(t *testing.T) {
+	files := []struct {
+		name    string
+		content string
+	}{
+		{name: "/src/app/main.go", content: "package app"},
+		{name: "/src/app/mocks/store.go", content: "package app"},
+		{name: "/src/app/store_gen.go", content: "package app"},
+		{name: "/src/app/zz_generated.deepcopy.go", content: "package app"},
+		{name: "/src/app/kind_string.go", content: "package app"},
+		{name: "/src/app/api.pb.go", content: "// @generated by protoc-gen-custom\n\npackage app"},
+	}
+
+	pass := &analysis.Pass{Fset: token.NewFileSet(), Pkg: types.NewPackage("example.com/app", "app")}
+	for _, f := range files {
+		file, err := parser.ParseFile(pass.Fset, f.name, f.content, parser.ParseComments)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pass.Files = append(pass.Files, file)
+	}
+
+	tests := []struct {
+		exclude string
+		skip    []bool
+	}{
+		{exclude: "", skip: []bool{false, false, false, false, false, false}},
+		{exclude: "**/mocks/**", skip: []bool{false, true, false, false, false, false}},
+		{exclude: "*_gen.go,zz_generated.*", skip: []bool{false, false, true, true, false, false}},
+		{exclude: "app/*.go", skip: []bool{true, false, true, true, true, true}},
+		{exclude: "/src/app/main.go", skip: []bool{true, false, false, false, false, false}},
+		{exclude: `re:_string\.go$`, skip: []bool{false, false, false, false, true, false}},
+		{exclude: "marker:@generated", skip: []bool{false, false, false, false, false, true}},
+		{exclude: "pkg:example.com/...", skip: []bool{true, true, true, true, true, true}},
+		{exclude: "pkg:example.com/other/...", skip: []bool{false, false, false, false, false, false}},
+	}
+
+	for _, tt := range tests {
+		var exclude skip.Exclude
+		if err := exclude.Set(tt.exclude); err != nil {
+			t.Fatal(err)
+		}
+		if exclude.String() != tt.exclude {
+			t.Errorf("String() = %q, expected %q", exclude.String(), tt.exclude)
+		}
+
+		nodeFilter := skip.NewExcludeStrategy(pass, &exclude)
+		for i, file := range pass.Files {
+			if got := nodeFilter(file); got != tt.skip[i] {
+				t.Errorf("%s: nodeFilter(%q) = %v, expected %v", tt.exclude, files[i].name, got, tt.skip[i])
+			}
+		}
+	}
+
+	for _, pattern := range []string{"re:(", "[a-"} {
+		var exclude skip.Exclude
+		if err := exclude.Set(pattern); err == nil {
+			t.Errorf("Set(%q) expected error", pattern)
+		}
+	}
+}
+
+func TestNewExcludeStrategyPatterns(t *testing.T) {
+	pass := &analysis.Pass{Fset: token.NewFileSet(), Pkg: types.NewPackage("example.com/app/mocks", "mocks")}
+	file, err := parser.ParseFile(pass.Fset, "/src/app/mocks/mock_store.go", "// Generated by mockery.\n\npackage mocks", parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pass.Files = []*ast.File{file}
+
+	tests := []struct {
+		name    string
+		exclude string
+		skip    bool
+	}{
+		{name: "glob", exclude: "mock_*.go", skip: true},
+		{name: "glob mismatch", exclude: "mock_*_test.go", skip: false},
+		{name: "regexp", exclude: "re:/mocks/", skip: true},
+		{name: "regexp mismatch", exclude: `re:_test\.go$`, skip: false},
+		{name: "package", exclude: "pkg:example.com/.../mocks", skip: true},
+		{name: "package itself", exclude: "pkg:example.com/app/mocks/...", skip: true},
+		{name: "package mismatch", exclude: "pkg:example.com/app", skip: false},
+		{name: "marker", exclude: "marker:Generated by mockery", skip: true},
+		{name: "marker mismatch", exclude: "marker:DO NOT EDIT", skip: false},
+	}
+
+	for _, tt := range tests {
+		var exclude skip.Exclude
+		if err := exclude.Set(tt.exclude); err != nil {
+			t.Fatal(err)
+		}
+		if got := skip.NewExcludeStrategy(pass, &exclude)(file); got != tt.skip {
+			t.Errorf("%s: nodeFilter() = %v, expected %v", tt.name, got, tt.skip)
+		}
+	}
+}
+
+func TestNewExcludeStrategyClasses(t *testing.T) {
+	tests := []struct {
+		exclude  string
+		filename string
+		skip     bool
+	}{
+		{exclude: "[!a]*.go", filename: "b.go", skip: true},
+		{exclude: "[!a]*.go", filename: "a.go", skip: false},
+		{exclude: "[^a]*.go", filename: "a.go", skip: false},
+		{exclude: "x[a!].go", filename: "x!.go", skip: true},
+		{exclude: "x[a!].go", filename: "x^.go", skip: false},
+	}
+
+	for _, tt := range tests {
+		pass := &analysis.Pass{Fset: token.NewFileSet(), Pkg: types.NewPackage("example.com/app", "app")}
+		file, err := parser.ParseFile(pass.Fset, "/src/app/"+tt.filename, "package app", 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pass.Files = []*ast.File{file}
+
+		var exclude skip.Exclude
+		if err := exclude.Set(tt.exclude); err != nil {
+			t.Fatal(err)
+		}
+		if got := skip.NewExcludeStrategy(pass, &exclude)(file); got != tt.skip {
+			t.Errorf("%s on %s: nodeFilter() = %v, expected %v", tt.exclude, tt.filename, got, tt.skip)
+		}
+	}
+}
+
+func TestCombinators(t *testing.T) {
+	yes := skip.NodeFilter(func(ast.Node) bool { return true })
+	no := skip.NodeFilter(func(ast.Node) bool { return false })
+	node := ast.NewIdent("x")
+
+	tests := []struct {
+		name       string
+		nodeFilter skip.NodeFilter
+		skip       bool
+	}{
+		{name: "Any()", nodeFilter: skip.Any[skip.NodeFilter](), skip: false},
+		{name: "Any(no, yes)", nodeFilter: skip.Any(no, yes), skip: true},
+		{name: "Any(no, no)", nodeFilter: skip.Any(no, no), skip: false},
+		{name: "All()", nodeFilter: skip.All[skip.NodeFilter](), skip: true},
+		{name: "All(yes, yes)", nodeFilter: skip.All(yes, yes), skip: true},
+		{name: "All(yes, no)", nodeFilter: skip.All(yes, no), skip: false},
+		{name: "Not(yes)", nodeFilter: skip.Not(yes), skip: false},
+		{name: "Not(Any(no, no))", nodeFilter: skip.Not(skip.Any(no, no)), skip: true},
+	}
+
+	for _, tt := range tests {
+		if got := tt.nodeFilter(node); got != tt.skip {
+			t.Errorf("%s: nodeFilter() = %v, expected %v", tt.name, got, tt.skip)
+		}
+	}
+
+	fileFilter := skip.All(skip.FileFilter(ast.IsGenerated), skip.Not(skip.FileFilter(func(file *ast.File) bool {
+		return file.Name.Name == "main"
+	})))
+	if got := fileFilter(&ast.File{Name: ast.NewIdent("main")}); got {
+		t.Errorf("fileFilter() = %v, expected false", got)
+	}
+}
+
+func TestNewRangeStrategy(t *testing.T) {
+	ranges := []skip.Range{{Start: 50, End: 60}, {Start: 10, End: 20}, {Start: 15, End: 30}, {Start: 30, End: 35}}
+	nodeFilter := skip.NewRangeStrategy(ranges...)
+
+	if ranges[0].Start != 50 {
+		t.Errorf("NewRangeStrategy() modified its arguments: %v", ranges)
+	}
+
+	tests := []struct {
+		pos  token.Pos
+		skip bool
+	}{
+		{pos: 9, skip: false},
+		{pos: 10, skip: true},
+		{pos: 25, skip: true},
+		{pos: 35, skip: true},
+		{pos: 36, skip: false},
+		{pos: 49, skip: false},
+		{pos: 60, skip: true},
+		{pos: 61, skip: false},
+	}
+
+	for _, tt := range tests {
+		if got := nodeFilter(&ast.Ident{NamePos: tt.pos}); got != tt.skip {
+			t.Errorf("nodeFilter(%d) = %v, expected %v", tt.pos, got, tt.skip)
+		}
+	}
+
+	if got := skip.NewRangeStrategy()(&ast.Ident{NamePos: 1}); got {
+		t.Errorf("nodeFilter() without ranges = %v, expected false", got)
+	}
+}
+
+func TestNewOverlapStrategy(t *testing.T) {
+	ranges := []skip.Range{{Start: 50, End: 60}, {Start: 10, End: 20}, {Start: 15, End: 30}}
+	nodeFilter := skip.NewOverlapStrategy(ranges...)
+
+	if ranges[0].Start != 50 {
+		t.Errorf("NewOverlapStrategy() modified its arguments: %v", ranges)
+	}
+
+	tests := []struct {
+		start, end token.Pos
+		skip       bool
+	}{
+		{start: 1, end: 9, skip: false},
+		{start: 1, end: 10, skip: true},
+		{start: 25, end: 26, skip: true},
+		{start: 31, end: 49, skip: false},
+		{start: 31, end: 70, skip: true},
+		{start: 60, end: 70, skip: true},
+		{start: 61, end: 70, skip: false},
+	}
+
+	for _, tt := range tests {
+		node := &ast.BlockStmt{Lbrace: tt.start, Rbrace: tt.end - 1}
+		if got := nodeFilter(node); got != tt.skip {
+			t.Errorf("nodeFilter(%d-%d) = %v, expected %v", tt.start, tt.end, got, tt.skip)
+		}
+	}
+
+	if got := skip.NewOverlapStrategy()(&ast.Ident{NamePos: 1}); got {
+		t.Errorf("nodeFilter() without ranges = %v, expected false", got)
+	}
+}
+
+// benchmarkRanges returns n non-overlapping ranges of 10 positions, spaced 20
+// positions apart, in reverse order.
+func benchmarkRanges(n int) []skip.Range {
+	ranges := make([]skip.Range, n)
+	for i := range ranges {
+		start := token.Pos((n-i)*20 + 1)
+		ranges[i] = skip.Range{Start: start, End: start + 10}
+	}
+	return ranges
+}
+
+func BenchmarkNewRangeStrategy(b *testing.B) {
+	for _, n := range []int{100, 1000, 10000} {
+		ranges := benchmarkRanges(n)
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			for b.Loop() {
+				skip.NewRangeStrategy(ranges...)
+			}
+		})
+	}
+}
+
+func BenchmarkRangeStrategyLookup(b *testing.B) {
+	for _, n := range []int{100, 1000, 10000} {
+		nodeFilter := skip.NewRangeStrategy(benchmarkRanges(n)...)
+		nodes := make([]ast.Node, 1024)
+		for i := range nodes {
+			nodes[i] = &ast.Ident{NamePos: token.Pos(i * n * 20 / len(nodes))}
+		}
+
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			var i int
+			for b.Loop() {
+				nodeFilter(nodes[i%len(nodes)])
+				i++
+			}
+		})
+	}
+}
@@ -0,0 +1,469 @@
+// Package watch implements gocheck -watch, which re-analyzes packages as their
+// files change and reports the diagnostics appearing and disappearing.
+package watch
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/tools/go/packages"
+
+	"github.com/abemedia/gocheck/check"
+)
+
+// Run analyzes the packages matching the patterns, then polls the Go files of
+// their modules every interval, re-analyzing the packages whose files changed
+// along with the packages importing them, until ctx is done.
+//
+// The loaded packages are kept in memory between changes, and each change loads
+// and type-checks only the affected packages once, using the export data of
+// their dependencies, unless packages were added or removed. The result cache
+// isn't used. Polling only reads the directories whose entries changed, and
+// files are hashed if their modification time or size changed, so saving a
+// file without changing it doesn't re-analyze anything.
+//
+// Diagnostics are written as they appear and disappear, prefixed with "+" and
+// "-" respectively. Errors analyzing changed packages, such as syntax errors
+// while a file is being edited, are written too, and the packages are analyzed
+// again on their next change.
+func Run(ctx context.Context, patterns []string, opts check.Options, w io.Writer, interval time.Duration) error {
+	root := opts.Dir
+	if root == "" {
+		root = "."
+	}
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return err
+	}
+
+	wt := &watcher{
+		patterns: patterns,
+		opts:     opts,
+		w:        w,
+		root:     root,
+		tree:     make(map[string]time.Time),
+		files:    make(map[string]fileState),
+		results:  make(map[string][]check.Result),
+	}
+
+	if err := wt.reset(ctx); err != nil {
+		return err
+	}
+	if err := wt.analyze(ctx, slices.Collect(maps.Keys(wt.pkgs))); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var failed []string // directories to retry
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		changed, err := wt.poll()
+		if err != nil {
+			return err
+		}
+		if len(changed) == 0 {
+			continue
+		}
+
+		changed = append(changed, failed...)
+		if err := wt.update(ctx, changed); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			fmt.Fprintln(w, "gocheck:", err)
+			failed = changed // retry on the next change
+			continue
+		}
+		failed = nil
+	}
+}
+
+// watcher holds the package graph, the state of the watched files and the
+// diagnostics between runs.
+type watcher struct {
+	patterns []string
+	opts     check.Options
+	w        io.Writer
+	root     string // directory paths are written relative to
+
+	pkgs    map[string][]*packages.Package // loaded packages per directory
+	ids     map[string]string              // directories of the packages by ID
+	imports map[string]map[string]bool     // package directories imported by each package directory
+	tree    map[string]time.Time           // modification times of the watched directories
+	files   map[string]fileState           // states of the watched Go files
+	results map[string][]check.Result      // diagnostics per package directory
+}
+
+// load loads the packages matching the patterns for analysis.
+func (wt *watcher) load(ctx context.Context, patterns []string) ([]*packages.Package, error) {
+	return packages.Load(&packages.Config{
+		Context: ctx,
+		Mode:    check.LoadMode | packages.NeedModule,
+		Dir:     wt.opts.Dir,
+		Tests:   wt.opts.Tests,
+	}, patterns...)
+}
+
+// reset loads the package graph of the patterns, and starts watching the
+// modules of its packages.
+func (wt *watcher) reset(ctx context.Context) error {
+	pkgs, err := wt.load(ctx, wt.patterns)
+	if err != nil {
+		return err
+	}
+
+	wt.pkgs, wt.ids, wt.imports = make(map[string][]*packages.Package), make(map[string]string), make(map[string]map[string]bool)
+	wt.add(pkgs)
+
+	roots := make(map[string]bool)
+	for _, pkg := range pkgs {
+		if pkg.Module != nil && pkg.Module.Dir != "" {
+			roots[pkg.Module.Dir] = true
+		} else if pkg.Dir != "" {
+			roots[pkg.Dir] = true
+		}
+	}
+	for dir := range wt.pkgs {
+		roots[dir] = true // directories ignored by the go command, such as testdata
+	}
+	for _, root := range slices.Sorted(maps.Keys(roots)) {
+		if _, ok := wt.tree[root]; !ok {
+			if err := wt.walk(root, make(map[string]bool)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// reload loads the packages in the directories again, replacing them in the
+// package graph.
+func (wt *watcher) reload(ctx context.Context, dirs []string) error {
+	pkgs, err := wt.load(ctx, dirs)
+	if err != nil {
+		return err
+	}
+
+	maps.DeleteFunc(wt.ids, func(_, dir string) bool { return slices.Contains(dirs, dir) })
+	for _, dir := range dirs {
+		delete(wt.pkgs, dir)
+		delete(wt.imports, dir)
+	}
+	wt.add(pkgs)
+
+	return nil
+}
+
+// add adds the packages to the package graph.
+func (wt *watcher) add(pkgs []*packages.Package) {
+	for _, pkg := range pkgs {
+		if pkg.Dir != "" {
+			wt.pkgs[pkg.Dir] = append(wt.pkgs[pkg.Dir], pkg)
+			wt.ids[pkg.ID] = pkg.Dir
+		}
+	}
+
+	for _, pkg := range pkgs {
+		for _, imp := range pkg.Imports {
+			dir, ok := wt.ids[imp.ID]
+			if !ok || pkg.Dir == "" || dir == pkg.Dir {
+				continue
+			}
+			if wt.imports[pkg.Dir] == nil {
+				wt.imports[pkg.Dir] = make(map[string]bool)
+			}
+			wt.imports[pkg.Dir][dir] = true
+		}
+	}
+}
+
+// update updates the package graph and re-analyzes the packages in the changed
+// directories and their importers. Only these packages are loaded again, unless
+// the files of a directory without packages changed or all files of a package
+// were removed, which may add or remove packages matching the patterns.
+func (wt *watcher) update(ctx context.Context, changed []string) error {
+	reset := false
+	for _, dir := range changed {
+		_, ok := wt.pkgs[dir]
+		if hasFiles := wt.hasFiles(dir); ok != hasFiles {
+			reset = true
+		}
+	}
+	if reset {
+		if err := wt.reset(ctx); err != nil {
+			return err
+		}
+	}
+
+	importers := make(map[string][]string)
+	for dir, imports := range wt.imports {
+		for imp := range imports {
+			importers[imp] = append(importers[imp], dir)
+		}
+	}
+
+	affected := make(map[string]bool)
+	queue := slices.Clone(changed)
+	for len(queue) > 0 {
+		dir := queue[0]
+		queue = queue[1:]
+		if affected[dir] {
+			continue
+		}
+		affected[dir] = true
+		queue = append(queue, importers[dir]...)
+	}
+
+	var dirs []string
+	for dir := range affected {
+		if _, ok := wt.pkgs[dir]; ok {
+			dirs = append(dirs, dir)
+		} else if _, ok := wt.results[dir]; ok {
+			// The package was removed.
+			wt.report(dir, nil)
+		}
+	}
+	slices.Sort(dirs)
+
+	// The importers are loaded again too, as their types depend on the
+	// changed packages.
+	if !reset && len(dirs) > 0 {
+		if err := wt.reload(ctx, dirs); err != nil {
+			return err
+		}
+	}
+
+	return wt.analyze(ctx, dirs)
+}
+
+// analyze analyzes the loaded packages in the directories and reports the
+// changes in their diagnostics. The packages must have been loaded together.
+func (wt *watcher) analyze(ctx context.Context, dirs []string) error {
+	if len(dirs) == 0 {
+		return nil
+	}
+	slices.Sort(dirs)
+
+	var pkgs []*packages.Package
+	for _, dir := range dirs {
+		pkgs = append(pkgs, wt.pkgs[dir]...)
+	}
+	results, err := check.Analyze(ctx, pkgs, wt.opts.Analyzers)
+	if err != nil {
+		return err
+	}
+
+	byDir := make(map[string][]check.Result, len(dirs))
+	for _, dir := range dirs {
+		byDir[dir] = []check.Result{}
+	}
+	for _, r := range results {
+		dir := filepath.Dir(r.Position.Filename)
+		byDir[dir] = append(byDir[dir], r)
+	}
+
+	for _, dir := range slices.Sorted(maps.Keys(byDir)) {
+		wt.report(dir, byDir[dir])
+	}
+	return nil
+}
+
+// report writes the diagnostics of the directory which were resolved or are
+// new, and records them. Moved diagnostics are reported as both.
+func (wt *watcher) report(dir string, results []check.Result) {
+	old := make(map[string]bool, len(wt.results[dir]))
+	for _, r := range wt.results[dir] {
+		old[wt.format(r)] = true
+	}
+	current := make(map[string]bool, len(results))
+	for _, r := range results {
+		current[wt.format(r)] = true
+	}
+
+	for _, r := range wt.results[dir] {
+		if line := wt.format(r); !current[line] {
+			fmt.Fprintln(wt.w, "-", line)
+		}
+	}
+	for _, r := range results {
+		if line := wt.format(r); !old[line] {
+			fmt.Fprintln(wt.w, "+", line)
+		}
+	}
+
+	if results == nil {
+		delete(wt.results, dir)
+	} else {
+		wt.results[dir] = results
+	}
+}
+
+// format formats a diagnostic with its filename relative to the root if the
+// file is within it.
+func (wt *watcher) format(r check.Result) string {
+	pos := r.Position
+	if rel, err := filepath.Rel(wt.root, pos.Filename); err == nil && filepath.IsLocal(rel) {
+		pos.Filename = rel
+	}
+	return fmt.Sprintf("%s: %s", pos, r.Message)
+}
+
+// fileState identifies the version of a file.
+type fileState struct {
+	modTime time.Time
+	size    int64
+	sum     [sha256.Size]byte
+}
+
+// poll returns the directories containing Go files which were added, removed
+// or modified since the last poll. Only the directories whose modification
+// time changed are read, which is the case when their entries change.
+func (wt *watcher) poll() ([]string, error) {
+	changed := make(map[string]bool)
+
+	for _, dir := range slices.Sorted(maps.Keys(wt.tree)) {
+		info, err := os.Stat(dir)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				return nil, err
+			}
+			delete(wt.tree, dir) // its files are removed below
+			continue
+		}
+		if info.ModTime().Equal(wt.tree[dir]) {
+			continue
+		}
+		wt.tree[dir] = info.ModTime()
+
+		entries, err := os.ReadDir(dir)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		for _, e := range entries {
+			path := filepath.Join(dir, e.Name())
+			if _, ok := wt.tree[path]; ok {
+				continue
+			}
+			if e.IsDir() && !skipDir(e.Name()) {
+				if err := wt.walk(path, changed); err != nil {
+					return nil, err
+				}
+			} else if _, ok := wt.files[path]; !ok && isGoFile(e) {
+				if err := wt.refresh(path, changed); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	for _, path := range slices.Sorted(maps.Keys(wt.files)) {
+		if err := wt.refresh(path, changed); err != nil {
+			return nil, err
+		}
+	}
+
+	return slices.Sorted(maps.Keys(changed)), nil
+}
+
+// walk starts watching the directory and its subdirectories, skipping those
+// ignored by the go command, and adds the directories of the Go files which
+// weren't watched before or changed to changed.
+func (wt *watcher) walk(root string, changed map[string]bool) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil // removed while walking
+			}
+			return err
+		}
+		if d.IsDir() {
+			if path != root && skipDir(d.Name()) {
+				return filepath.SkipDir
+			}
+			info, err := d.Info()
+			if err != nil {
+				if os.IsNotExist(err) {
+					return filepath.SkipDir
+				}
+				return err
+			}
+			wt.tree[path] = info.ModTime()
+			return nil
+		}
+		if !isGoFile(d) {
+			return nil
+		}
+		return wt.refresh(path, changed)
+	})
+}
+
+// refresh updates the state of a Go file, adding its directory to changed if
+// the file was added, removed or modified. Files are only hashed if their
+// modification time or size changed, and only count as modified if their
+// content did.
+func (wt *watcher) refresh(path string, changed map[string]bool) error {
+	old, ok := wt.files[path]
+
+	info, err := os.Stat(path)
+	if err == nil && ok && old.size == info.Size() && old.modTime.Equal(info.ModTime()) {
+		return nil
+	}
+	var b []byte
+	if err == nil {
+		b, err = os.ReadFile(path)
+	}
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return err
+		}
+		if ok {
+			delete(wt.files, path)
+			changed[filepath.Dir(path)] = true
+		}
+		return nil
+	}
+
+	state := fileState{modTime: info.ModTime(), size: info.Size(), sum: sha256.Sum256(b)}
+	wt.files[path] = state
+	if !ok || old.sum != state.sum {
+		changed[filepath.Dir(path)] = true
+	}
+	return nil
+}
+
+// hasFiles reports whether the directory contains watched Go files.
+func (wt *watcher) hasFiles(dir string) bool {
+	for path := range wt.files {
+		if filepath.Dir(path) == dir {
+			return true
+		}
+	}
+	return false
+}
+
+// skipDir reports whether the go command ignores directories of the name.
+func skipDir(name string) bool {
+	return name == "testdata" || name == "vendor" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")
+}
+
+// isGoFile reports whether the entry is a Go file.
+func isGoFile(e fs.DirEntry) bool {
+	return !e.IsDir() && strings.HasSuffix(e.Name(), ".go")
+}
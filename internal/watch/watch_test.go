@@ -0,0 +1,257 @@
+package watch_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/abemedia/gocheck/check"
+	"github.com/abemedia/gocheck/internal/watch"
+	"github.com/abemedia/gocheck/untested"
+)
+
+func writeFile(t *testing.T, name, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "go.mod"), "module example.com/m\n\ngo 1.22\n")
+	writeFile(t, filepath.Join(dir, "a", "a.go"), "package a\n\ntype T struct{ X, Y int }\n\nfunc F() {}\n")
+	writeFile(t, filepath.Join(dir, "b", "b.go"), "package b\n\nimport \"example.com/m/a\"\n\nvar _ = a.T{Y: 1, X: 2}\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r, w := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- watch.Run(ctx, []string{"./..."}, check.Options{Dir: dir, Tests: true}, w, 10*time.Millisecond)
+		w.Close()
+	}()
+
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		for s := bufio.NewScanner(r); s.Scan(); {
+			lines <- s.Text()
+		}
+	}()
+
+	expect := func(want ...string) {
+		t.Helper()
+		for _, want := range want {
+			select {
+			case got := <-lines:
+				if got != want {
+					t.Fatalf("got %q, want %q", got, want)
+				}
+			case <-time.After(30 * time.Second):
+				t.Fatalf("timed out waiting for %q", want)
+			}
+		}
+	}
+
+	expect(
+		`+ `+filepath.Join("a", "a.go")+`:5:1: exported function "F" has no test`,
+		`+ `+filepath.Join("b", "b.go")+`:5:12: struct literal fields are out of order`,
+	)
+
+	// Testing the function resolves its diagnostic.
+	writeFile(t, filepath.Join(dir, "a", "a_test.go"), "package a\n\nimport \"testing\"\n\nfunc TestF(t *testing.T) { F() }\n")
+	expect(`- ` + filepath.Join("a", "a.go") + `:5:1: exported function "F" has no test`)
+
+	// Changing a package re-analyzes its importers.
+	writeFile(t, filepath.Join(dir, "a", "a.go"), "package a\n\ntype T struct{ Y, X int }\n\nfunc F() {}\n")
+	expect(`- ` + filepath.Join("b", "b.go") + `:5:12: struct literal fields are out of order`)
+
+	// Errors are reported until the package is fixed.
+	writeFile(t, filepath.Join(dir, "c", "c.go"), "package c\n\nfunc C( {}\n")
+	select {
+	case got := <-lines:
+		if !strings.HasPrefix(got, "gocheck: ") {
+			t.Fatalf("got %q, want an error", got)
+		}
+	case <-time.After(30 * time.Second):
+		t.Fatal("timed out waiting for error")
+	}
+	writeFile(t, filepath.Join(dir, "c", "c.go"), "package c\n\nfunc C() {}\n")
+	want := `+ ` + filepath.Join("c", "c.go") + `:3:1: exported function "C" has no test`
+	for got := ""; got != want; {
+		select {
+		case got = <-lines:
+			if got != want && (strings.HasPrefix(got, "+") || strings.HasPrefix(got, "-")) {
+				t.Fatalf("got %q, want %q", got, want)
+			}
+		case <-time.After(30 * time.Second):
+			t.Fatalf("timed out waiting for %q", want)
+		}
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunUnchanged(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "go.mod"), "module example.com/m\n\ngo 1.22\n")
+	writeFile(t, filepath.Join(dir, "a", "a.go"), "package a\n")
+
+	// Report the number of runs to tell when the package is analyzed again.
+	var runs atomic.Int32
+	counter := &analysis.Analyzer{
+		Name: "counter",
+		Doc:  "report the number of runs",
+		Run: func(pass *analysis.Pass) (any, error) {
+			pass.Reportf(pass.Files[0].Package, "run %d", runs.Add(1))
+			return nil, nil
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r, w := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		opts := check.Options{Dir: dir, Analyzers: []*analysis.Analyzer{counter}}
+		done <- watch.Run(ctx, []string{"./..."}, opts, w, 10*time.Millisecond)
+		w.Close()
+	}()
+
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		for s := bufio.NewScanner(r); s.Scan(); {
+			lines <- s.Text()
+		}
+	}()
+
+	next := func() string {
+		t.Helper()
+		select {
+		case got := <-lines:
+			return got
+		case <-time.After(30 * time.Second):
+			t.Fatal("timed out")
+			return ""
+		}
+	}
+
+	name := filepath.Join("a", "a.go")
+	if got, want := next(), "+ "+name+":1:1: run 1"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	// Touching a file doesn't re-analyze its package.
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "a", "a.go"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-lines:
+		t.Fatalf("got %q after touching a file, want nothing", got)
+	case <-time.After(500 * time.Millisecond):
+	}
+
+	writeFile(t, filepath.Join(dir, "a", "a.go"), "package a\n\nfunc F() {}\n")
+	for _, want := range []string{"- " + name + ":1:1: run 1", "+ " + name + ":1:1: run 2"} {
+		if got := next(); got != want {
+			t.Fatalf("got %q, want %q", got, want)
+		}
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunSummary(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "go.mod"), "module example.com/m\n\ngo 1.22\n")
+	writeFile(t, filepath.Join(dir, "a", "a.go"), "package a\n\nfunc F() {}\n")
+	writeFile(t, filepath.Join(dir, "b", "b.go"), "package b\n\nfunc G() {}\n")
+
+	summary := filepath.Join(t.TempDir(), "summary.json")
+	analyzers := check.Analyzers()
+	for _, a := range analyzers {
+		if a.Name == "untested" {
+			a.Flags.Set("summary", summary)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r, w := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		opts := check.Options{Dir: dir, Tests: true, Analyzers: analyzers}
+		done <- watch.Run(ctx, []string{"./..."}, opts, w, 10*time.Millisecond)
+		w.Close()
+	}()
+
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		for s := bufio.NewScanner(r); s.Scan(); {
+			lines <- s.Text()
+		}
+	}()
+
+	expect := func(want ...string) {
+		t.Helper()
+		for _, want := range want {
+			select {
+			case got := <-lines:
+				if got != want {
+					t.Fatalf("got %q, want %q", got, want)
+				}
+			case <-time.After(30 * time.Second):
+				t.Fatalf("timed out waiting for %q", want)
+			}
+		}
+	}
+
+	expect(
+		`+ `+filepath.Join("a", "a.go")+`:3:1: exported function "F" has no test`,
+		`+ `+filepath.Join("b", "b.go")+`:3:1: exported function "G" has no test`,
+	)
+
+	writeFile(t, filepath.Join(dir, "a", "a_test.go"), "package a\n\nimport \"testing\"\n\nfunc TestF(t *testing.T) { F() }\n")
+	expect(`- ` + filepath.Join("a", "a.go") + `:3:1: exported function "F" has no test`)
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	// The summary is rewritten by each run rather than collecting all of them.
+	var got struct {
+		Packages []untested.Summary `json:"packages"`
+	}
+	data, err := os.ReadFile(summary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := []untested.Summary{{Package: "example.com/m/a", Tested: 1, Total: 1, Ratio: 1}}
+	if !reflect.DeepEqual(got.Packages, want) {
+		t.Errorf("packages = %+v, want %+v", got.Packages, want)
+	}
+}
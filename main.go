@@ -2,12 +2,94 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
 	"golang.org/x/tools/go/analysis/multichecker"
 
-	"github.com/abemedia/gocheck/fieldorder"
-	"github.com/abemedia/gocheck/untested"
+	"github.com/abemedia/gocheck/check"
+	"github.com/abemedia/gocheck/internal/config"
+	"github.com/abemedia/gocheck/internal/driver"
+	"github.com/abemedia/gocheck/internal/lsp"
+	"github.com/abemedia/gocheck/internal/report"
 )
 
 func main() {
-	multichecker.Main(fieldorder.NewAnalyzer(), untested.NewAnalyzer())
+	if len(os.Args) > 1 && os.Args[1] == "untested-report" {
+		if err := report.Run(os.Args[2:], os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, "gocheck untested-report:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "cache" {
+		if len(os.Args) != 3 || os.Args[2] != "clean" {
+			fmt.Fprintln(os.Stderr, "usage: gocheck cache clean")
+			os.Exit(2)
+		}
+		if err := check.CleanCache(""); err != nil {
+			fmt.Fprintln(os.Stderr, "gocheck cache:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	analyzers := check.Analyzers()
+	enabled := analyzers
+
+	cfg, err := config.Find(".")
+	if err == nil && cfg != nil {
+		enabled, err = cfg.Apply(analyzers, driver.Flags(os.Args[1:], analyzers...))
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "gocheck: config:", err)
+		os.Exit(1)
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "lsp" {
+		if err := lsp.Serve(context.Background(), os.Stdin, os.Stdout, enabled...); err != nil {
+			fmt.Fprintln(os.Stderr, "gocheck lsp:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Analyzers disabled in the configuration are disabled by flags preceding
+	// the command line, so they can be enabled again.
+	if cfg != nil {
+		os.Args = append(os.Args[:1], cfg.Args(os.Args[1:])...)
+	}
+
+	if args := os.Args[1:]; driver.Format(args, analyzers...) != "text" || driver.Supports(args, analyzers...) {
+		// As with multichecker, text diagnostics are written to stderr and
+		// reported by the exit code.
+		text := driver.Format(args, analyzers...) == "text"
+		stdout := os.Stdout
+		if text {
+			stdout = os.Stderr
+		}
+
+		n, err := driver.Run(args, stdout, analyzers...)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "gocheck:", err)
+			os.Exit(1)
+		}
+		if text && n > 0 {
+			os.Exit(3)
+		}
+		return
+	}
+
+	// Accept the driver's flags so they're listed in the help. Runs using
+	// multichecker's own flags, such as -fix, don't use the result cache.
+	flag.Func("tags", driver.TagsUsage, driver.SetTags)
+	flag.String("format", "text", "output format: "+strings.Join(driver.Formats(), ", "))
+	flag.String("cache", "off", "result cache mode: off, read or readwrite")
+	flag.Bool("watch", false, "re-analyze packages as their files change, reporting new and resolved diagnostics")
+
+	multichecker.Main(analyzers...)
 }
@@ -0,0 +1,6 @@
+package notests
+
+// Exported should not trigger a warning (exported)
+func Exported() {}
+
+func unused() {} // want "unexported function \"unused\" is unreachable"
@@ -0,0 +1,66 @@
+package unreachable
+
+import "fmt"
+
+var handlers = map[string]func(){
+	"handler": handler,
+}
+
+func init() {
+	setup()
+}
+
+// Exported should not trigger a warning (exported)
+func Exported() {
+	helper()
+}
+
+func helper() {
+	nested()
+}
+
+func nested() {}
+
+func setup() {}
+
+func handler() {}
+
+// unused should trigger a warning (not reachable)
+func unused() { // want "unexported function \"unused\" is unreachable"
+	alsoUnused()
+}
+
+func alsoUnused() { // want "unexported function \"alsoUnused\" is unreachable"
+	fmt.Println("unused")
+}
+
+func onlyTested() {}
+
+type shape interface {
+	area() float64
+}
+
+type square struct{ side float64 }
+
+// area should not trigger a warning (may be called through shape)
+func (s square) area() float64 { return s.side * s.side }
+
+// perimeter should trigger a warning (not reachable)
+func (s square) perimeter() float64 { return 4 * s.side } // want "unexported method \"square.perimeter\" is unreachable"
+
+// Area should not trigger a warning (exported)
+func (s square) Area() float64 { return s.area() }
+
+//go:linkname linked
+func linked() {}
+
+// kept should not trigger a warning (suppressed)
+//
+//nolint:unreachable // kept for debugging
+func kept() {}
+
+// want +1 "//gocheck:ignore directive does not suppress anything"
+func reached() {} //gocheck:ignore unreachable called by Reach
+
+// Reach should not trigger a warning (exported)
+func Reach() { reached() }
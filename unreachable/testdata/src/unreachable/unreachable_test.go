@@ -0,0 +1,10 @@
+package unreachable
+
+import "testing"
+
+func TestOnlyTested(t *testing.T) {
+	onlyTested()
+	testHelper()
+}
+
+func testHelper() {}
@@ -0,0 +1,247 @@
+// Package unreachable checks for unexported functions and methods which are
+// not reachable from any exported symbol, init, main or test.
+package unreachable
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+
+	"github.com/abemedia/gocheck/internal/refgraph"
+	"github.com/abemedia/gocheck/internal/skip"
+)
+
+var excludeFlag skip.Exclude
+
+// NewAnalyzer returns an analyzer that reports unexported functions and
+// methods which are not reachable from any exported symbol, init, main or test.
+func NewAnalyzer() *analysis.Analyzer {
+	analyzer := &analysis.Analyzer{
+		Name:     "unreachable",
+		Doc:      "check for unexported functions and methods not reachable from exported symbols, init, main or tests",
+		Run:      run,
+		Requires: []*analysis.Analyzer{inspect.Analyzer},
+	}
+
+	excludeFlag = skip.Exclude{}
+	analyzer.Flags.Var(&excludeFlag, "exclude", "comma-separated list of file globs, re:, pkg: or marker: patterns to skip")
+
+	return analyzer
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	if len(pass.Files) == 0 {
+		return nil, nil
+	}
+
+	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	shouldSkip := skip.Any(
+		skip.NewFileStrategy(pass, func(file *ast.File) bool {
+			return isTestFile(pass, file) || ast.IsGenerated(file)
+		}),
+		skip.NewExcludeStrategy(pass, &excludeFlag),
+	)
+	directives := skip.NewDirectives(pass)
+
+	graph := refgraph.New()
+	graph.Add(pass.Files, pass.TypesInfo, func(obj types.Object) bool {
+		return obj.Pkg() == pass.Pkg
+	})
+
+	// Package-level variables are initialized even if unused.
+	var roots []string
+	for _, file := range pass.Files {
+		roots = append(roots, graph.FileReferences(file)...)
+	}
+	for key := range graph.Vars {
+		roots = append(roots, key)
+	}
+
+	testNames := testFileNames(pass)
+	for key, funcDecl := range graph.Decls {
+		if isRoot(pass, funcDecl) || testNames[funcDecl.Name.Name] {
+			roots = append(roots, key)
+		}
+	}
+
+	reachable := graph.Reachable(roots)
+	dynamic := interfaceMethods(pass)
+
+	nodeFilter := []ast.Node{(*ast.FuncDecl)(nil)}
+	inspect.Preorder(nodeFilter, func(n ast.Node) {
+		funcDecl := n.(*ast.FuncDecl)
+		if funcDecl.Name.Name == "_" || shouldSkip(funcDecl) {
+			return
+		}
+
+		fn, ok := pass.TypesInfo.Defs[funcDecl.Name].(*types.Func)
+		if !ok {
+			return
+		}
+
+		// Unexported methods may be called dynamically through interfaces.
+		key := refgraph.Key(fn)
+		if reachable[key] || (funcDecl.Recv != nil && dynamic[fn.Name()]) {
+			return
+		}
+		if _, ok := directives.Suppressed(funcDecl); ok {
+			return
+		}
+
+		start, end := funcDecl.Pos(), funcDecl.End()
+		if funcDecl.Doc != nil {
+			start = funcDecl.Doc.Pos()
+		}
+
+		// Remove trailing comments on the declaration's last line too.
+		file := fileOf(pass, funcDecl)
+		line := pass.Fset.Position(end).Line
+		for _, cg := range file.Comments {
+			if cg.Pos() >= end && pass.Fset.Position(cg.Pos()).Line == line {
+				end = cg.End()
+			}
+		}
+
+		name := refgraph.Name(fn)
+		pass.Report(analysis.Diagnostic{
+			Pos:     funcDecl.Pos(),
+			End:     funcDecl.End(),
+			Message: fmt.Sprintf("unexported %s %q is unreachable", funcType(funcDecl), name),
+			SuggestedFixes: []analysis.SuggestedFix{{
+				Message:   "Remove " + funcType(funcDecl) + " " + name,
+				TextEdits: []analysis.TextEdit{{Pos: start, End: end}},
+			}},
+		})
+	})
+
+	directives.Report(skip.Not(shouldSkip))
+
+	return nil, nil
+}
+
+// testFileNames returns the names referenced by the package's test files if
+// they are not part of the pass, as is the case when analyzing a package
+// without its tests. As the files are not type-checked, functions and methods
+// are considered referenced if any identifier in the tests has their name.
+func testFileNames(pass *analysis.Pass) map[string]bool {
+	for _, file := range pass.Files {
+		if isTestFile(pass, file) {
+			return nil
+		}
+	}
+
+	dir := filepath.Dir(pass.Fset.Position(pass.Files[0].Pos()).Filename)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	names := make(map[string]bool)
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), "_test.go") {
+			continue
+		}
+
+		// External tests can only reference exported symbols.
+		file, err := parser.ParseFile(token.NewFileSet(), filepath.Join(dir, entry.Name()), nil, parser.SkipObjectResolution)
+		if err != nil || file.Name.Name != pass.Pkg.Name() {
+			continue
+		}
+
+		ast.Inspect(file, func(n ast.Node) bool {
+			if ident, ok := n.(*ast.Ident); ok {
+				names[ident.Name] = true
+			}
+			return true
+		})
+	}
+
+	return names
+}
+
+// isRoot determines if a function is an entry point of the package: exported
+// functions and methods, init, main, functions declared in test files and
+// functions exported to C or linked by name.
+func isRoot(pass *analysis.Pass, funcDecl *ast.FuncDecl) bool {
+	name := funcDecl.Name.Name
+	switch {
+	case ast.IsExported(name), isTestFile(pass, funcDecl):
+		return true
+	case funcDecl.Recv == nil && (name == "init" || (name == "main" && pass.Pkg.Name() == "main")):
+		return true
+	}
+
+	if funcDecl.Doc != nil {
+		for _, c := range funcDecl.Doc.List {
+			if strings.HasPrefix(c.Text, "//export ") || strings.HasPrefix(c.Text, "//go:linkname ") {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// interfaceMethods returns the names of the unexported methods of interfaces
+// used in the package. Unexported methods can only satisfy interfaces declared
+// in the same package, so these are the only ones which may be called
+// dynamically.
+func interfaceMethods(pass *analysis.Pass) map[string]bool {
+	names := make(map[string]bool)
+
+	add := func(typ types.Type) {
+		if iface, ok := typ.Underlying().(*types.Interface); ok {
+			for i := range iface.NumMethods() {
+				if name := iface.Method(i).Name(); !ast.IsExported(name) {
+					names[name] = true
+				}
+			}
+		}
+	}
+
+	for _, tv := range pass.TypesInfo.Types {
+		add(tv.Type)
+	}
+	for _, obj := range pass.TypesInfo.Defs {
+		if typeName, ok := obj.(*types.TypeName); ok {
+			add(typeName.Type())
+		}
+	}
+
+	return names
+}
+
+// fileOf returns the file containing the node.
+func fileOf(pass *analysis.Pass, node ast.Node) *ast.File {
+	for _, file := range pass.Files {
+		if file.FileStart <= node.Pos() && node.Pos() <= file.FileEnd {
+			return file
+		}
+	}
+
+	return nil
+}
+
+// isTestFile determines if the node is located in a test file.
+func isTestFile(pass *analysis.Pass, node ast.Node) bool {
+	return strings.HasSuffix(pass.Fset.Position(node.Pos()).Filename, "_test.go")
+}
+
+// funcType returns "method" for methods or "function" for functions, used for
+// error message formatting.
+func funcType(fn *ast.FuncDecl) string {
+	if fn.Recv != nil && len(fn.Recv.List) > 0 {
+		return "method"
+	}
+
+	return "function"
+}
@@ -0,0 +1,14 @@
+package unreachable_test
+
+import (
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+
+	"github.com/abemedia/gocheck/unreachable"
+)
+
+func TestUnreachable(t *testing.T) {
+	testdata := analysistest.TestData()
+	analysistest.RunWithSuggestedFixes(t, testdata, unreachable.NewAnalyzer(), "unreachable", "notests")
+}
@@ -0,0 +1,210 @@
+package untested
+
+import (
+	"go/token"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
+
+	"github.com/abemedia/gocheck/internal/refgraph"
+)
+
+// defaultAssertFuncs is the default list of functions, or packages whose
+// functions and methods, count as assertions on the values passed to them.
+const defaultAssertFuncs = "reflect.DeepEqual,errors.Is,errors.As,bytes.Equal,slices.Equal,maps.Equal," +
+	"github.com/google/go-cmp/cmp.Equal,github.com/google/go-cmp/cmp.Diff," +
+	"github.com/stretchr/testify/assert,github.com/stretchr/testify/require"
+
+// collectAssertedReferences returns the functions of the target package which
+// tests call and assert on, keyed by the declared test function or helper
+// making the call. A call is asserted if its results flow into a comparison, a
+// condition, a t.Error* or t.Fatal* call, an assertion function, a test helper
+// or are returned. Calls to functions without results, and functions used as
+// values, always count as the flow can't be followed.
+func collectAssertedReferences(pkg *packages.Package, isTarget func(*types.Package) bool) map[string][]string {
+	prog := ssa.NewProgram(pkg.Fset, 0)
+
+	created := make(map[*types.Package]bool)
+	var create func(p *types.Package)
+	create = func(p *types.Package) {
+		if created[p] {
+			return
+		}
+		created[p] = true
+		for _, imp := range p.Imports() {
+			create(imp)
+		}
+		if p != pkg.Types {
+			prog.CreatePackage(p, nil, nil, true)
+		}
+	}
+	create(pkg.Types)
+
+	ssaPkg := prog.CreatePackage(pkg.Types, pkg.Syntax, pkg.TypesInfo, false)
+	ssaPkg.Build()
+
+	isTargetFunc := func(fn *types.Func) bool {
+		return fn.Pkg() != nil && (isTarget(fn.Pkg()) || fn.Pkg() == pkg.Types)
+	}
+	isTestCode := func(pos token.Pos) bool {
+		return pos.IsValid() && strings.HasSuffix(pkg.Fset.Position(pos).Filename, "_test.go")
+	}
+
+	assertFuncs := strings.Split(assertFuncsFlag, ",")
+
+	refs := make(map[string][]string)
+	for fn := range ssautil.AllFunctions(prog) {
+		if fn.Pkg != ssaPkg || !isTestCode(fn.Pos()) {
+			continue
+		}
+
+		// Calls in function literals are made by their enclosing declaration.
+		caller := ""
+		decl := fn
+		for decl.Parent() != nil {
+			decl = decl.Parent()
+		}
+		if obj, ok := decl.Object().(*types.Func); ok {
+			caller = refgraph.Key(obj.Origin())
+		}
+
+		for _, block := range fn.Blocks {
+			for _, instr := range block.Instrs {
+				call, isCall := instr.(ssa.CallInstruction)
+
+				// Functions used as values, e.g. in test tables.
+				for _, op := range instr.Operands(nil) {
+					if isCall && op == &call.Common().Value {
+						continue
+					}
+					if f, ok := (*op).(*ssa.Function); ok {
+						if obj, ok := f.Object().(*types.Func); ok && isTargetFunc(obj) {
+							refs[caller] = append(refs[caller], refgraph.Key(obj.Origin()))
+						}
+					}
+				}
+
+				if !isCall {
+					continue
+				}
+
+				callee := call.Common().StaticCallee()
+				if callee == nil {
+					continue
+				}
+				obj, ok := callee.Object().(*types.Func)
+				if !ok || !isTargetFunc(obj) {
+					continue
+				}
+
+				v, ok := call.(*ssa.Call)
+				if obj.Signature().Results().Len() == 0 || (ok && isAsserted(v, assertFuncs, isTestCode)) {
+					refs[caller] = append(refs[caller], refgraph.Key(obj.Origin()))
+				}
+			}
+		}
+	}
+
+	return refs
+}
+
+// isAsserted follows the def-use chains of a value to determine whether it
+// flows into an assertion.
+func isAsserted(v ssa.Value, assertFuncs []string, isTestCode func(token.Pos) bool) bool {
+	seen := make(map[ssa.Value]bool)
+	queue := []ssa.Value{v}
+
+	follow := func(v ssa.Value) {
+		if v != nil && !seen[v] {
+			seen[v] = true
+			queue = append(queue, v)
+		}
+	}
+
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+
+		refs := v.Referrers()
+		if refs == nil {
+			continue
+		}
+
+		for _, instr := range *refs {
+			switch instr := instr.(type) {
+			case *ssa.If, *ssa.Return:
+				return true
+			case *ssa.BinOp:
+				switch instr.Op {
+				case token.EQL, token.NEQ, token.LSS, token.LEQ, token.GTR, token.GEQ:
+					return true
+				}
+				follow(instr)
+			case ssa.CallInstruction:
+				if isAssertion(instr.Common(), assertFuncs, isTestCode) {
+					return true
+				}
+				if call, ok := instr.(*ssa.Call); ok {
+					follow(call)
+				}
+			case *ssa.Store:
+				// Values stored in variables, fields or elements flow into their
+				// loads, e.g. variadic arguments stored in a slice.
+				if instr.Val == v {
+					follow(instr.Addr)
+					switch addr := instr.Addr.(type) {
+					case *ssa.FieldAddr:
+						follow(addr.X)
+					case *ssa.IndexAddr:
+						follow(addr.X)
+					}
+				}
+			case *ssa.MakeClosure:
+				for i, binding := range instr.Bindings {
+					if binding == v {
+						follow(instr.Fn.(*ssa.Function).FreeVars[i])
+					}
+				}
+			case ssa.Value:
+				follow(instr)
+			}
+		}
+	}
+
+	return false
+}
+
+// isAssertion determines if a call asserts on its arguments: t.Error* and
+// t.Fatal* calls, calls to the configured assertion functions and calls to
+// helpers declared in test files.
+func isAssertion(call *ssa.CallCommon, assertFuncs []string, isTestCode func(token.Pos) bool) bool {
+	var fn *types.Func
+	if call.IsInvoke() {
+		fn = call.Method
+	} else if callee := call.StaticCallee(); callee != nil {
+		if isTestCode(callee.Pos()) {
+			return true
+		}
+		fn, _ = callee.Object().(*types.Func)
+	}
+	if fn == nil || fn.Pkg() == nil {
+		return false
+	}
+
+	pkgPath := fn.Pkg().Path()
+	if pkgPath == "testing" && (strings.HasPrefix(fn.Name(), "Error") || strings.HasPrefix(fn.Name(), "Fatal")) {
+		return true
+	}
+
+	name := refgraph.Key(fn)
+	for _, assertFunc := range assertFuncs {
+		if assertFunc = strings.TrimSpace(assertFunc); assertFunc == name || assertFunc == pkgPath {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,49 @@
+package untested
+
+import (
+	"go/ast"
+	"go/token"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// isDeprecated determines if a doc comment has a paragraph starting with
+// "Deprecated: ", following the Go convention for deprecation notices.
+func isDeprecated(doc *ast.CommentGroup) bool {
+	if doc == nil {
+		return false
+	}
+
+	for paragraph := range strings.SplitSeq(doc.Text(), "\n\n") {
+		if strings.HasPrefix(strings.TrimSpace(paragraph), "Deprecated: ") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// collectDeprecatedTypes returns the names of the package's deprecated types,
+// whose methods are considered deprecated too.
+func collectDeprecatedTypes(pass *analysis.Pass) map[string]bool {
+	deprecated := make(map[string]bool)
+
+	for _, file := range pass.Files {
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+
+			for _, spec := range gen.Specs {
+				typeSpec := spec.(*ast.TypeSpec)
+				if isDeprecated(typeSpec.Doc) || (len(gen.Specs) == 1 && isDeprecated(gen.Doc)) {
+					deprecated[typeSpec.Name.Name] = true
+				}
+			}
+		}
+	}
+
+	return deprecated
+}
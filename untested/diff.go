@@ -0,0 +1,257 @@
+package untested
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/token"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/abemedia/gocheck/internal/skip"
+)
+
+// changes holds the changes to check, as read from a diff file or git.
+type changes struct {
+	root  string   // directory the paths in the diff are relative to
+	patch []byte   // unified diff
+	added []string // absolute filenames of files changed entirely
+}
+
+// filter returns a node filter determining if a node of the pass overlaps a
+// changed line.
+func (c *changes) filter(pass *analysis.Pass) (skip.NodeFilter, error) {
+	files := make(map[string]*token.File, len(pass.Files))
+	for _, file := range pass.Files {
+		tf := pass.Fset.File(file.Pos())
+		files[tf.Name()] = tf
+	}
+
+	var ranges []skip.Range
+	for _, name := range c.added {
+		if tf := files[name]; tf != nil {
+			ranges = append(ranges, skip.Range{Start: token.Pos(tf.Base()), End: token.Pos(tf.Base() + tf.Size())})
+		}
+	}
+
+	err := parseUnifiedDiff(bytes.NewReader(c.patch), c.root, func(filename string, start, end int) {
+		if tf := files[filename]; tf != nil && start <= tf.LineCount() {
+			ranges = append(ranges, lineRange(tf, start, end))
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return skip.NewOverlapStrategy(ranges...), nil
+}
+
+// lineRange returns the range spanning the lines from start to end of a file.
+func lineRange(tf *token.File, start, end int) skip.Range {
+	r := skip.Range{Start: tf.LineStart(start), End: token.Pos(tf.Base() + tf.Size())}
+	if end < tf.LineCount() {
+		r.End = tf.LineStart(end+1) - 1
+	}
+	return r
+}
+
+// diffResult holds the changes of a repository root and flags, which are the
+// same for all packages of a run.
+type diffResult struct {
+	once    sync.Once
+	changes *changes
+	err     error
+}
+
+// loadChanges returns the changes according to the diff file set by the diff
+// flag or, if unset, relative to the revision set by the since flag, including
+// files not yet tracked by git.
+func (s *runState) loadChanges(dir string) (*changes, error) {
+	root := ""
+	if diffFlag == "" {
+		out, err := git(dir, "rev-parse", "--show-toplevel")
+		if err != nil {
+			return nil, err
+		}
+		root = strings.TrimSpace(string(out))
+	}
+
+	v, _ := s.diffs.LoadOrStore(strings.Join([]string{root, sinceFlag, diffFlag}, "\x00"), &diffResult{})
+	res := v.(*diffResult)
+	res.once.Do(func() {
+		if diffFlag != "" {
+			res.changes, res.err = readDiffFile(diffFlag)
+		} else {
+			res.changes, res.err = gitChanges(root, sinceFlag)
+		}
+	})
+
+	return res.changes, res.err
+}
+
+// readDiffFile reads a unified diff file. Paths are relative to the working
+// directory, as with patch.
+func readDiffFile(filename string) (*changes, error) {
+	patch, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+
+	// Report malformed diffs once rather than for every package.
+	if err := parseUnifiedDiff(bytes.NewReader(patch), wd, func(string, int, int) {}); err != nil {
+		return nil, err
+	}
+
+	return &changes{root: wd, patch: patch}, nil
+}
+
+// gitChanges returns the changes to the repository at root since its
+// merge-base with the given revision, so changes made to the revision after
+// branching off are ignored. Untracked files are considered changed entirely.
+func gitChanges(root, rev string) (*changes, error) {
+	out, err := git(root, "merge-base", rev, "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	base := strings.TrimSpace(string(out))
+
+	out, err = git(root, "diff", "--no-color", "--no-ext-diff", "--src-prefix=a/", "--dst-prefix=b/", "-U0", base, "--")
+	if err != nil {
+		return nil, err
+	}
+
+	c := &changes{root: root, patch: out}
+	if err := parseUnifiedDiff(bytes.NewReader(c.patch), root, func(string, int, int) {}); err != nil {
+		return nil, err
+	}
+
+	out, err = git(root, "ls-files", "--others", "--exclude-standard")
+	if err != nil {
+		return nil, err
+	}
+
+	for name := range strings.Lines(string(out)) {
+		if name = strings.TrimSpace(name); strings.HasSuffix(name, ".go") {
+			c.added = append(c.added, filepath.Join(root, filepath.FromSlash(name)))
+		}
+	}
+
+	return c, nil
+}
+
+// git runs a git command in dir and returns its output.
+func git(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+
+	return out, nil
+}
+
+// parseUnifiedDiff parses the added lines of a unified diff, resolving paths
+// relative to root, and calls add with the changed lines of each file. Deleted
+// lines mark their surrounding lines as changed.
+func parseUnifiedDiff(r io.Reader, root string, add func(filename string, start, end int)) error {
+	var filename string
+	var line, oldN, newN int
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		text := scanner.Text()
+
+		if oldN > 0 || newN > 0 {
+			switch {
+			case strings.HasPrefix(text, "+"):
+				if filename != "" {
+					add(filename, line, line)
+				}
+				line++
+				newN--
+			case strings.HasPrefix(text, "-"):
+				if filename != "" {
+					add(filename, max(line-1, 1), line)
+				}
+				oldN--
+			case strings.HasPrefix(text, `\`):
+			default:
+				line++
+				oldN--
+				newN--
+			}
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(text, "+++ "):
+			name, _, _ := strings.Cut(strings.TrimPrefix(text, "+++ "), "\t")
+			filename = ""
+			if name != "/dev/null" {
+				filename = filepath.Join(root, filepath.FromSlash(strings.TrimPrefix(name, "b/")))
+			}
+		case strings.HasPrefix(text, "@@ "):
+			var err error
+			if line, oldN, newN, err = parseHunkHeader(text); err != nil {
+				return err
+			}
+			// Hunks without new lines start at the line preceding the deletion.
+			if newN == 0 {
+				line++
+			}
+		}
+	}
+
+	return scanner.Err()
+}
+
+// parseHunkHeader parses a hunk header of the form "@@ -l,s +l,s @@",
+// returning the first line of the new file and the old and new line counts.
+func parseHunkHeader(header string) (line, oldN, newN int, err error) {
+	fields := strings.Fields(header)
+	if len(fields) < 4 || !strings.HasPrefix(fields[1], "-") || !strings.HasPrefix(fields[2], "+") {
+		return 0, 0, 0, fmt.Errorf("invalid hunk header %q", header)
+	}
+
+	if _, oldN, err = parseHunkRange(fields[1][1:]); err != nil {
+		return 0, 0, 0, fmt.Errorf("invalid hunk header %q: %w", header, err)
+	}
+	if line, newN, err = parseHunkRange(fields[2][1:]); err != nil {
+		return 0, 0, 0, fmt.Errorf("invalid hunk header %q: %w", header, err)
+	}
+
+	return line, oldN, newN, nil
+}
+
+// parseHunkRange parses a "start,count" hunk range where count defaults to 1.
+func parseHunkRange(s string) (start, count int, err error) {
+	startStr, countStr, ok := strings.Cut(s, ",")
+	if start, err = strconv.Atoi(startStr); err != nil {
+		return 0, 0, err
+	}
+	if !ok {
+		return start, 1, nil
+	}
+	if count, err = strconv.Atoi(countStr); err != nil {
+		return 0, 0, err
+	}
+
+	return start, count, nil
+}
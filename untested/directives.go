@@ -0,0 +1,148 @@
+package untested
+
+import (
+	"go/ast"
+	"go/token"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/abemedia/gocheck/internal/skip"
+)
+
+// ignoreDirective is the comment directive used to suppress diagnostics.
+const ignoreDirective = "//untested:ignore"
+
+// directive is a parsed //untested:ignore comment.
+type directive struct {
+	comment *ast.Comment
+	reason  string
+	used    bool
+}
+
+// directives holds the //untested:ignore directives of a package, indexed by
+// the scope they apply to.
+type directives struct {
+	funcs map[string]*directive // keyed by function name, e.g. "Type.Method"
+	types map[string]*directive // keyed by type name
+	files map[string]*directive // keyed by filename
+	pkg   *directive
+	all   []*directive
+}
+
+// parseDirectives collects //untested:ignore directives from the doc comments of
+// functions, methods and types, and from comments preceding the package clause,
+// which apply to the whole file, or to the whole package if the file is doc.go.
+func parseDirectives(pass *analysis.Pass, shouldSkipNode skip.NodeFilter) *directives {
+	d := &directives{
+		funcs: make(map[string]*directive),
+		types: make(map[string]*directive),
+		files: make(map[string]*directive),
+	}
+
+	for _, file := range pass.Files {
+		if shouldSkipNode(file) {
+			continue
+		}
+
+		filename := pass.Fset.Position(file.Pos()).Filename
+		for _, cg := range file.Comments {
+			if cg.End() > file.Package {
+				break
+			}
+			if dir := d.parse(cg); dir != nil {
+				if filepath.Base(filename) == "doc.go" {
+					d.pkg = dir
+				} else {
+					d.files[filename] = dir
+				}
+			}
+		}
+
+		for _, decl := range file.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				if dir := d.parse(decl.Doc); dir != nil {
+					d.funcs[getFuncDeclName(decl)] = dir
+				}
+			case *ast.GenDecl:
+				if decl.Tok != token.TYPE {
+					continue
+				}
+				declDir := d.parse(decl.Doc)
+				for _, spec := range decl.Specs {
+					typeSpec := spec.(*ast.TypeSpec)
+					if dir := d.parse(typeSpec.Doc); dir != nil {
+						d.types[typeSpec.Name.Name] = dir
+					} else if declDir != nil {
+						d.types[typeSpec.Name.Name] = declDir
+					}
+				}
+			}
+		}
+	}
+
+	return d
+}
+
+// parse returns the directive in the given comment group, if any.
+func (d *directives) parse(cg *ast.CommentGroup) *directive {
+	if cg == nil {
+		return nil
+	}
+
+	for _, c := range cg.List {
+		rest, ok := strings.CutPrefix(c.Text, ignoreDirective)
+		if !ok || (rest != "" && rest[0] != ' ' && rest[0] != '\t') {
+			continue
+		}
+
+		dir := &directive{comment: c, reason: strings.TrimSpace(rest)}
+		d.all = append(d.all, dir)
+		return dir
+	}
+
+	return nil
+}
+
+// lookup returns the most specific directive suppressing the given function,
+// marking it as used, or nil if there is none.
+func (d *directives) lookup(pass *analysis.Pass, funcDecl *ast.FuncDecl) *directive {
+	key := getFuncDeclName(funcDecl)
+	filename := pass.Fset.Position(funcDecl.Pos()).Filename
+
+	dir := d.funcs[key]
+	if dir == nil {
+		if typeName, _, ok := strings.Cut(key, "."); ok {
+			dir = d.types[typeName]
+		}
+	}
+	if dir == nil {
+		dir = d.files[filename]
+	}
+	if dir == nil {
+		dir = d.pkg
+	}
+	if dir != nil {
+		dir.used = true
+	}
+
+	return dir
+}
+
+// report reports directives without a reason and directives which did not
+// suppress any diagnostic, limited to directives matching the filter.
+func (d *directives) report(pass *analysis.Pass, filter func(ast.Node) bool) {
+	for _, dir := range d.all {
+		if !filter(dir.comment) {
+			continue
+		}
+		if dir.reason == "" {
+			pass.ReportRangef(dir.comment, "%s directive requires a reason", ignoreDirective)
+		}
+		if !dir.used {
+			pass.ReportRangef(dir.comment, "%s directive does not suppress anything", ignoreDirective)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package untested
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/types"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// suggestTestFix returns a suggested fix that appends a table-driven test stub
+// for the given function to the test file matching its source file (e.g.
+// "parse.go" is tested in "parse_test.go"). No fix is returned if a test with
+// the generated name already exists, or if the test file isn't part of the
+// pass, as suggested fixes can only edit existing files.
+func suggestTestFix(pass *analysis.Pass, funcDecl *ast.FuncDecl) []analysis.SuggestedFix {
+	fn, ok := pass.TypesInfo.Defs[funcDecl.Name].(*types.Func)
+	if !ok {
+		return nil
+	}
+
+	sig := fn.Type().(*types.Signature)
+	if sig.TypeParams().Len() > 0 || sig.RecvTypeParams().Len() > 0 {
+		return nil
+	}
+
+	testName := "Test" + strings.ReplaceAll(getFuncDeclName(funcDecl), ".", "_")
+	if pass.Pkg.Scope().Lookup(testName) != nil {
+		return nil
+	}
+
+	testFile := findTestFile(pass, funcDecl)
+	if testFile == nil {
+		return nil
+	}
+
+	// Use the local names of packages already imported by the test file and
+	// record any packages that still need to be imported.
+	imported := make(map[string]string, len(testFile.Imports))
+	for _, spec := range testFile.Imports {
+		path, _ := strconv.Unquote(spec.Path.Value)
+		imported[path] = importName(pass, spec, path)
+	}
+
+	var missing []string
+	addImport := func(path, name string) string {
+		if local, ok := imported[path]; ok {
+			return local
+		}
+		imported[path] = name
+		missing = append(missing, path)
+		return name
+	}
+
+	qualifier := func(pkg *types.Package) string {
+		if pkg == pass.Pkg {
+			return ""
+		}
+		return addImport(pkg.Path(), pkg.Name())
+	}
+
+	stub := generateTestStub(testName, fn, qualifier, addImport)
+	if stub == nil {
+		return nil
+	}
+
+	edits := make([]analysis.TextEdit, 0, 2)
+	if len(missing) > 0 {
+		slices.Sort(missing)
+		edits = append(edits, importEdit(testFile, missing))
+	}
+	edits = append(edits, analysis.TextEdit{Pos: testFile.FileEnd, End: testFile.FileEnd, NewText: stub})
+
+	return []analysis.SuggestedFix{{Message: "Add test stub " + testName, TextEdits: edits}}
+}
+
+// testFilename returns the name of the test file matching the source file of
+// the given function declaration.
+func testFilename(pass *analysis.Pass, funcDecl *ast.FuncDecl) string {
+	filename := pass.Fset.Position(funcDecl.Pos()).Filename
+	return strings.TrimSuffix(filename, ".go") + "_test.go"
+}
+
+// findTestFile returns the test file matching the source file of the given
+// function declaration, or nil if it is not part of the pass.
+func findTestFile(pass *analysis.Pass, funcDecl *ast.FuncDecl) *ast.File {
+	testFilename := testFilename(pass, funcDecl)
+
+	for _, file := range pass.Files {
+		if pass.Fset.Position(file.Pos()).Filename == testFilename && file.Name.Name == pass.Pkg.Name() {
+			return file
+		}
+	}
+
+	return nil
+}
+
+// importName returns the local name under which an import spec makes the
+// package with the given path available.
+func importName(pass *analysis.Pass, spec *ast.ImportSpec, path string) string {
+	if spec.Name != nil {
+		return spec.Name.Name
+	}
+	if pkgName, ok := pass.TypesInfo.Implicits[spec].(*types.PkgName); ok {
+		return pkgName.Imported().Name()
+	}
+	return path[strings.LastIndex(path, "/")+1:]
+}
+
+// importEdit returns an edit adding the given import paths to the file, either
+// after its last import or, if it has none, after the package clause.
+func importEdit(file *ast.File, paths []string) analysis.TextEdit {
+	var buf bytes.Buffer
+
+	if len(file.Imports) > 0 {
+		last := file.Imports[len(file.Imports)-1]
+		for _, decl := range file.Decls {
+			if gen, ok := decl.(*ast.GenDecl); ok && gen.Pos() <= last.Pos() && last.End() <= gen.End() {
+				if gen.Lparen.IsValid() {
+					for _, path := range paths {
+						fmt.Fprintf(&buf, "\n\t%q", path)
+					}
+					return analysis.TextEdit{Pos: last.End(), End: last.End(), NewText: buf.Bytes()}
+				}
+
+				for _, path := range paths {
+					fmt.Fprintf(&buf, "\nimport %q", path)
+				}
+				return analysis.TextEdit{Pos: gen.End(), End: gen.End(), NewText: buf.Bytes()}
+			}
+		}
+	}
+
+	buf.WriteString("\n\nimport (")
+	for _, path := range paths {
+		fmt.Fprintf(&buf, "\n\t%q", path)
+	}
+	buf.WriteString("\n)")
+
+	return analysis.TextEdit{Pos: file.Name.End(), End: file.Name.End(), NewText: buf.Bytes()}
+}
+
+// generateTestStub generates the source of a table-driven test for the given
+// function, with a field per parameter and expected result. The qualifier and
+// addImport functions are used to refer to packages from the test file.
+func generateTestStub(
+	testName string,
+	fn *types.Func,
+	qualifier types.Qualifier,
+	addImport func(path, name string) string,
+) []byte {
+	sig := fn.Type().(*types.Signature)
+	used := map[string]bool{"name": true}
+
+	unique := func(name string, i int) string {
+		if name == "" || name == "_" {
+			name = "arg" + strconv.Itoa(i)
+		}
+		for used[name] {
+			name += strconv.Itoa(i)
+		}
+		used[name] = true
+		return name
+	}
+
+	var fields, args []string
+
+	fields = append(fields, "name string")
+
+	if recv := sig.Recv(); recv != nil {
+		name := unique("receiver", 0)
+		fields = append(fields, name+" "+types.TypeString(recv.Type(), qualifier))
+		args = append(args, name)
+	}
+
+	params := make([]string, 0, sig.Params().Len())
+	for i := range sig.Params().Len() {
+		param := sig.Params().At(i)
+		name := unique(param.Name(), i)
+		fields = append(fields, name+" "+types.TypeString(param.Type(), qualifier))
+
+		arg := "tt." + name
+		if sig.Variadic() && i == sig.Params().Len()-1 {
+			arg += "..."
+		}
+		params = append(params, arg)
+	}
+
+	results := sig.Results()
+	hasErr := results.Len() > 0 && types.Identical(results.At(results.Len()-1).Type(), types.Universe.Lookup("error").Type())
+
+	var got, wants []string
+	for i := range results.Len() {
+		if hasErr && i == results.Len()-1 {
+			fields = append(fields, unique("wantErr", i)+" bool")
+			got = append(got, "err")
+			continue
+		}
+
+		suffix := ""
+		if i > 0 {
+			suffix = strconv.Itoa(i)
+		}
+		want := unique("want"+suffix, i)
+		fields = append(fields, want+" "+types.TypeString(results.At(i).Type(), qualifier))
+		got = append(got, "got"+suffix)
+		wants = append(wants, want)
+	}
+
+	call := fn.Name() + "(" + strings.Join(params, ", ") + ")"
+	if len(args) > 0 {
+		call = "tt." + args[0] + "." + call
+	}
+
+	testing := addImport("testing", "testing")
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "\nfunc %s(t *%s.T) {\n", testName, testing)
+	fmt.Fprintf(&buf, "tests := []struct {\n%s\n}{\n// TODO: Add test cases.\n}\n\n", strings.Join(fields, "\n"))
+	fmt.Fprintf(&buf, "for _, tt := range tests {\nt.Run(tt.name, func(t *%s.T) {\n", testing)
+
+	if len(got) > 0 {
+		fmt.Fprintf(&buf, "%s := %s\n", strings.Join(got, ", "), call)
+	} else {
+		fmt.Fprintf(&buf, "%s\n", call)
+	}
+
+	if hasErr {
+		fmt.Fprintf(&buf, "if (err != nil) != tt.wantErr {\n")
+		fmt.Fprintf(&buf, "t.Fatalf(\"%s() error = %%v, wantErr %%v\", err, tt.wantErr)\n}\n", fn.Name())
+	}
+
+	if len(wants) > 0 {
+		reflect := addImport("reflect", "reflect")
+		for i, want := range wants {
+			fmt.Fprintf(&buf, "if !%s.DeepEqual(%s, tt.%s) {\n", reflect, got[i], want)
+			fmt.Fprintf(&buf, "t.Errorf(\"%s() %s = %%v, want %%v\", %s, tt.%s)\n}\n", fn.Name(), got[i], got[i], want)
+		}
+	}
+
+	buf.WriteString("})\n}\n}\n")
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil
+	}
+
+	return src
+}
@@ -0,0 +1,184 @@
+package untested
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/packages"
+)
+
+// wellKnownInterfaces declares the default interfaces of packages which may not
+// be imported by the analyzed package. Types can implement them without
+// importing their packages, as they only refer to predeclared types.
+var wellKnownInterfaces = sync.OnceValue(func() map[string]*types.Interface {
+	const src = `package wellknown
+
+type Stringer interface{ String() string }
+
+type Interface interface {
+	Len() int
+	Less(i, j int) bool
+	Swap(i, j int)
+}
+
+type Marshaler interface{ MarshalJSON() ([]byte, error) }
+`
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "wellknown.go", src, 0)
+	if err != nil {
+		panic(err)
+	}
+	pkg, err := new(types.Config).Check("wellknown", fset, []*ast.File{file}, nil)
+	if err != nil {
+		panic(err)
+	}
+
+	lookup := func(name string) *types.Interface {
+		return pkg.Scope().Lookup(name).Type().Underlying().(*types.Interface)
+	}
+	return map[string]*types.Interface{
+		"fmt.Stringer":            lookup("Stringer"),
+		"sort.Interface":          lookup("Interface"),
+		"encoding/json.Marshaler": lookup("Marshaler"),
+	}
+})
+
+// defaultInterfaces lists the interfaces whose methods are commonly only called
+// through the interface and are therefore treated as covered when their receiver
+// type is used by tests.
+const defaultInterfaces = "fmt.Stringer,error,sort.Interface,encoding/json.Marshaler"
+
+// parseInterfaces resolves a comma-separated list of qualified interface names
+// (e.g. "fmt.Stringer" or "encoding/json.Marshaler") to their types. Names which
+// cannot be resolved are ignored, including those of packages not imported by
+// pkg, directly or indirectly, other than the defaults.
+func parseInterfaces(pkg *types.Package, list string) []*types.Interface {
+	var ifaces []*types.Interface
+
+	for name := range strings.SplitSeq(list, ",") {
+		if iface := lookupInterface(pkg, strings.TrimSpace(name)); iface != nil {
+			ifaces = append(ifaces, iface)
+		}
+	}
+
+	return ifaces
+}
+
+// lookupInterface resolves a qualified interface name from the transitive
+// imports of pkg, so the interface shares type identity with the analyzed code.
+func lookupInterface(pkg *types.Package, name string) *types.Interface {
+	var obj types.Object
+
+	i := strings.LastIndex(name, ".")
+	if i < 0 {
+		obj = types.Universe.Lookup(name)
+	} else if imported := findImport(pkg, name[:i], make(map[*types.Package]bool)); imported != nil {
+		obj = imported.Scope().Lookup(name[i+1:])
+	} else {
+		return wellKnownInterfaces()[name]
+	}
+
+	if obj == nil {
+		return nil
+	}
+
+	iface, _ := obj.Type().Underlying().(*types.Interface)
+	return iface
+}
+
+// findImport returns the package with the given path from the transitive
+// imports of pkg, or nil if it is not imported.
+func findImport(pkg *types.Package, path string, seen map[*types.Package]bool) *types.Package {
+	if pkg.Path() == path {
+		return pkg
+	}
+
+	seen[pkg] = true
+	for _, imp := range pkg.Imports() {
+		if seen[imp] {
+			continue
+		}
+		if found := findImport(imp, path, seen); found != nil {
+			return found
+		}
+	}
+
+	return nil
+}
+
+// implementsInterface determines if a method declaration is part of one of the
+// given interfaces implemented by its receiver type, returning the receiver
+// type's name if so.
+func implementsInterface(pass *analysis.Pass, funcDecl *ast.FuncDecl, ifaces []*types.Interface) (string, bool) {
+	fn, ok := pass.TypesInfo.Defs[funcDecl.Name].(*types.Func)
+	if !ok {
+		return "", false
+	}
+
+	recv := fn.Type().(*types.Signature).Recv()
+	if recv == nil {
+		return "", false
+	}
+
+	typ := recv.Type()
+	if ptr, ok := typ.(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+
+	named, ok := typ.(*types.Named)
+	if !ok {
+		return "", false
+	}
+
+	for _, iface := range ifaces {
+		if !hasMethod(iface, fn.Name()) {
+			continue
+		}
+		if types.Implements(named, iface) || types.Implements(types.NewPointer(named), iface) {
+			return named.Obj().Name(), true
+		}
+	}
+
+	return "", false
+}
+
+// hasMethod determines if the interface's method set includes the named method.
+func hasMethod(iface *types.Interface, name string) bool {
+	for i := range iface.NumMethods() {
+		if iface.Method(i).Name() == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// collectTestTypes records the named types of the target package which are used
+// by expressions in the given test file, e.g. in composite literals, conversions
+// or as the result of calls.
+func collectTestTypes(file *ast.File, pkg *packages.Package, isTarget func(*types.Package) bool, testTypes map[string]bool) {
+	ast.Inspect(file, func(n ast.Node) bool {
+		expr, ok := n.(ast.Expr)
+		if !ok {
+			return true
+		}
+
+		typ := pkg.TypesInfo.TypeOf(expr)
+		if ptr, ok := typ.(*types.Pointer); ok {
+			typ = ptr.Elem()
+		}
+
+		if named, ok := typ.(*types.Named); ok {
+			if obj := named.Obj(); obj.Pkg() != nil && isTarget(obj.Pkg()) {
+				testTypes[obj.Name()] = true
+			}
+		}
+
+		return true
+	})
+}
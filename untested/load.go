@@ -0,0 +1,176 @@
+package untested
+
+import (
+	"go/token"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/packages"
+)
+
+// loadConfigs returns the configurations used to load the package in dir with
+// its tests. The first loads the default build, inheriting the driver's
+// environment including GOOS, GOARCH and the tags of its -tags flag, which are
+// added to GOFLAGS. It is followed by one configuration per build tag set in
+// the tags flag, so tests guarded by build constraints also contribute
+// coverage. The configurations share a file set, so positions of all loaded
+// packages can be compared.
+func loadConfigs(dir string) []*packages.Config {
+	fset := token.NewFileSet()
+	cfgs := []*packages.Config{newLoadConfig(fset, dir, nil, nil)}
+
+	for set := range strings.SplitSeq(tagsFlag, ";") {
+		var tags, env []string
+
+		for tag := range strings.SplitSeq(set, ",") {
+			tag = strings.TrimSpace(tag)
+			switch {
+			case tag == "":
+			case strings.Contains(tag, "="):
+				env = append(env, tag)
+			default:
+				tags = append(tags, tag)
+			}
+		}
+
+		if len(tags) > 0 || len(env) > 0 {
+			cfgs = append(cfgs, newLoadConfig(fset, dir, tags, env))
+		}
+	}
+
+	return cfgs
+}
+
+// newLoadConfig returns a configuration loading the package in dir with its
+// tests, using the given build tags and additional environment variables.
+func newLoadConfig(fset *token.FileSet, dir string, tags, env []string) *packages.Config {
+	cfg := &packages.Config{
+		Mode:  packages.LoadSyntax,
+		Fset:  fset,
+		Dir:   dir,
+		Env:   append(os.Environ(), env...),
+		Tests: true,
+	}
+
+	// Build flags override GOFLAGS, so keep the tags set by the driver.
+	if len(tags) > 0 {
+		tags = append(goflagsTags(), tags...)
+		cfg.BuildFlags = []string{"-tags=" + strings.Join(tags, ",")}
+	}
+
+	return cfg
+}
+
+// goflagsTags returns the build tags set through the GOFLAGS environment
+// variable, which the driver's -tags flag adds to. As with the go command, the
+// last -tags flag wins.
+func goflagsTags() []string {
+	var tags []string
+	for flag := range strings.FieldsSeq(os.Getenv("GOFLAGS")) {
+		if value, ok := strings.CutPrefix(strings.TrimLeft(flag, "-"), "tags="); ok {
+			tags = strings.Split(value, ",")
+		}
+	}
+
+	return tags
+}
+
+// loadTestPackages loads the package in dir with its tests once per
+// configuration returned by loadConfigs.
+func loadTestPackages(dir string) ([]*packages.Package, error) {
+	var pkgs []*packages.Package
+
+	for _, cfg := range loadConfigs(dir) {
+		loaded, err := packages.Load(cfg, ".")
+		if err != nil {
+			return nil, err
+		}
+		pkgs = append(pkgs, loaded...)
+	}
+
+	return pkgs, nil
+}
+
+// loadErrors returns the unique errors of the loaded packages, such as test
+// files failing to compile or missing test dependencies. Packages without type
+// information are reported as errors too, as they would yield no references.
+func loadErrors(pkgs []*packages.Package) []packages.Error {
+	var errs []packages.Error
+	seen := make(map[string]bool)
+
+	for _, pkg := range pkgs {
+		pkgErrs := pkg.Errors
+		if len(pkgErrs) == 0 && pkg.TypesInfo == nil {
+			pkgErrs = []packages.Error{{Msg: "no type information for " + pkg.ID, Kind: packages.UnknownError}}
+		}
+
+		// The compiler reports type errors again as a list error, so prefer the
+		// type checker's errors which carry precise positions.
+		hasTypeErrors := slices.ContainsFunc(pkgErrs, func(err packages.Error) bool {
+			return err.Kind == packages.TypeError
+		})
+
+		for _, err := range pkgErrs {
+			if hasTypeErrors && err.Kind == packages.ListError {
+				continue
+			}
+			if !seen[err.Error()] {
+				seen[err.Error()] = true
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return errs
+}
+
+// reportLoadErrors reports errors encountered loading the tests. Errors in files
+// of the pass are reported at their position, others at the package clause.
+func reportLoadErrors(pass *analysis.Pass, errs []packages.Error) {
+	for _, err := range errs {
+		pos, msg := pass.Files[0].Package, err.Msg
+
+		if filename, line, ok := parseErrorPos(err.Pos); ok {
+			msg = err.Error()
+			for _, file := range pass.Files {
+				if tf := pass.Fset.File(file.Pos()); tf.Name() == filename && line <= tf.LineCount() {
+					pos, msg = tf.LineStart(line), err.Msg
+					break
+				}
+			}
+		}
+
+		pass.Report(analysis.Diagnostic{Pos: pos, Category: "load", Message: "could not analyze tests: " + msg})
+	}
+}
+
+// parseErrorPos parses the filename and line of a "file:line:column" or
+// "file:line" position.
+func parseErrorPos(pos string) (string, int, bool) {
+	for range 2 {
+		i := strings.LastIndex(pos, ":")
+		if i < 0 {
+			return "", 0, false
+		}
+
+		n, err := strconv.Atoi(pos[i+1:])
+		if err != nil {
+			return "", 0, false
+		}
+
+		filename := pos[:i]
+		if j := strings.LastIndex(filename, ":"); j >= 0 {
+			if _, err := strconv.Atoi(filename[j+1:]); err == nil {
+				pos = filename
+				continue
+			}
+		}
+
+		return filename, n, true
+	}
+
+	return "", 0, false
+}
@@ -0,0 +1,97 @@
+package untested
+
+import (
+	"go/ast"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/packages"
+
+	"github.com/abemedia/gocheck/internal/refgraph"
+	"github.com/abemedia/gocheck/internal/skip"
+)
+
+// collectFileReferences marks the functions reachable from each test file of
+// the package, keyed by the test file's name.
+func collectFileReferences(pkgs []*packages.Package, graph *refgraph.Graph, fileReferences map[string]map[string]bool) {
+	fset := pkgs[0].Fset
+
+	roots := make(map[string][]string)
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Syntax {
+			if isTestFile(fset, file) {
+				filename := fset.Position(file.Pos()).Filename
+				roots[filename] = append(roots[filename], graph.FileReferences(file)...)
+			}
+		}
+	}
+
+	for key, funcDecl := range graph.Decls {
+		if isTestFile(fset, funcDecl) {
+			filename := fset.Position(funcDecl.Pos()).Filename
+			roots[filename] = append(roots[filename], key)
+		}
+	}
+
+	for filename, roots := range roots {
+		if fileReferences[filename] == nil {
+			fileReferences[filename] = make(map[string]bool)
+		}
+		for key := range graph.Reachable(roots) {
+			fileReferences[filename][key] = true
+		}
+	}
+}
+
+// reportUntestedFiles reports test files which don't test any function or
+// method declared in their source file, e.g. parser_test.go not testing
+// anything from parser.go. Only test files of the pass are reported, and
+// source files without functions are not considered.
+func reportUntestedFiles(
+	pass *analysis.Pass,
+	shouldSkipNode skip.NodeFilter,
+	isChanged func(ast.Node) bool,
+	refs *references,
+) {
+	declared := make(map[string][]string)
+	for _, file := range pass.Files {
+		if shouldSkipNode(file) {
+			continue
+		}
+
+		filename := testFileFor(pass.Fset.Position(file.Pos()).Filename)
+		for _, decl := range file.Decls {
+			if funcDecl, ok := decl.(*ast.FuncDecl); ok {
+				declared[filename] = append(declared[filename], refs.key(funcDecl))
+			}
+		}
+	}
+
+	for _, file := range pass.Files {
+		filename := pass.Fset.Position(file.Pos()).Filename
+		funcs := declared[filename]
+		if !isTestFile(pass.Fset, file) || len(funcs) == 0 || !isChanged(file) {
+			continue
+		}
+
+		tested := false
+		for _, key := range funcs {
+			if refs.files[filename][key] {
+				tested = true
+				break
+			}
+		}
+
+		if !tested {
+			pass.Reportf(file.Package, "%s does not test anything from %s",
+				filepath.Base(filename), strings.TrimSuffix(filepath.Base(filename), "_test.go")+".go")
+		}
+	}
+}
+
+// testFileFor returns the name of the test file corresponding to a source
+// file, e.g. parser_test.go for parser.go.
+func testFileFor(filename string) string {
+	return strings.TrimSuffix(filename, ".go") + "_test.go"
+}
@@ -0,0 +1,47 @@
+package untested
+
+import (
+	"go/ast"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/abemedia/gocheck/internal/skip"
+)
+
+// perfDirective is the comment directive marking performance-critical functions.
+const perfDirective = "//perf:critical"
+
+// isPerfCritical determines if a doc comment contains a //perf:critical directive.
+func isPerfCritical(doc *ast.CommentGroup) bool {
+	if doc == nil {
+		return false
+	}
+
+	for _, c := range doc.List {
+		rest, ok := strings.CutPrefix(c.Text, perfDirective)
+		if ok && (rest == "" || rest[0] == ' ' || rest[0] == '\t') {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isBenchmark determines if a function is a benchmark.
+func isBenchmark(funcDecl *ast.FuncDecl) bool {
+	return isTestFunction(funcDecl, "Benchmark")
+}
+
+// reportUnbenchmarked reports performance-critical functions which are not
+// reachable from any benchmark.
+func reportUnbenchmarked(pass *analysis.Pass, funcs []*ast.FuncDecl, refs *references, directives *skip.Directives) {
+	for _, funcDecl := range funcs {
+		if refs.benchmarked[refs.key(funcDecl)] {
+			continue
+		}
+		if _, ok := directives.Suppressed(funcDecl); !ok {
+			pass.ReportRangef(funcDecl, "performance-critical %s %q has no benchmark", getFuncType(funcDecl), getFuncDeclName(funcDecl))
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package untested
+
+// Result is the result of the untested analyzer for a package, describing the
+// test coverage of its exported functions and methods.
+type Result struct {
+	Package   string      `json:"package"`
+	Functions []*Function `json:"functions,omitempty"`
+	Error     string      `json:"error,omitempty"`
+}
+
+// Summary returns the summary of the package's functions. Ignored functions
+// don't count towards the total.
+func (r *Result) Summary() *Summary {
+	var total int
+	var untested []string
+	for _, fn := range r.Functions {
+		if fn.Ignored != "" {
+			continue
+		}
+		total++
+		if !fn.Tested {
+			untested = append(untested, fn.Name)
+		}
+	}
+	return NewSummary(r.Package, total, untested)
+}
+
+// Function describes the test coverage of an exported function or method.
+type Function struct {
+	Name     string   `json:"name"`              // qualified name, e.g. "Type.Method"
+	Kind     string   `json:"kind"`              // "function" or "method"
+	Position string   `json:"position"`          // position of the declaration
+	Tested   bool     `json:"tested"`            // whether tests cover the function
+	Ignored  string   `json:"ignored,omitempty"` // reason of the //untested:ignore directive
+	Tests    []string `json:"tests,omitempty"`   // test functions covering the function
+	Depth    int      `json:"depth,omitempty"`   // number of calls from the nearest test
+}
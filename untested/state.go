@@ -0,0 +1,36 @@
+package untested
+
+import (
+	"go/token"
+	"runtime"
+	"sync"
+	"weak"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// runState holds the state shared by the packages analyzed in one run.
+type runState struct {
+	diffs     sync.Map // *diffResult per repository root and flags
+	summaries sync.Map // *summaryFile per path
+}
+
+// runStates holds the state of each run, keyed by the file set shared by the
+// packages loaded together. Entries are removed once their file set is garbage
+// collected, so later runs in the same process, such as in watch mode, start
+// afresh instead of reusing stale changes or summaries.
+var runStates sync.Map
+
+// stateOf returns the state of the run the pass belongs to.
+func stateOf(pass *analysis.Pass) *runState {
+	key := weak.Make(pass.Fset)
+	if v, ok := runStates.Load(key); ok {
+		return v.(*runState)
+	}
+
+	v, loaded := runStates.LoadOrStore(key, &runState{})
+	if !loaded {
+		runtime.AddCleanup(pass.Fset, func(key weak.Pointer[token.FileSet]) { runStates.Delete(key) }, key)
+	}
+	return v.(*runState)
+}
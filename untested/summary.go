@@ -0,0 +1,108 @@
+package untested
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// Summary describes how many of the exported functions and methods of a
+// package, or of all analyzed packages, have tests. Packages whose tests could
+// not be analyzed have an error and no functions.
+type Summary struct {
+	Package  string   `json:"package,omitempty"`
+	Tested   int      `json:"tested"`
+	Total    int      `json:"total"`
+	Ratio    float64  `json:"ratio"`
+	Untested []string `json:"untested,omitempty"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// NewSummary returns the summary of a package from the number of functions
+// checked and the names of those which are untested.
+func NewSummary(pkgPath string, total int, untested []string) *Summary {
+	return &Summary{
+		Package:  pkgPath,
+		Tested:   total - len(untested),
+		Total:    total,
+		Ratio:    Ratio(total-len(untested), total),
+		Untested: untested,
+	}
+}
+
+// Ratio returns the ratio of tested to total functions, which is 1 if there
+// are no functions.
+func Ratio(tested, total int) float64 {
+	if total == 0 {
+		return 1
+	}
+	return float64(tested) / float64(total)
+}
+
+// summaryFile accumulates package summaries written to a JSON file.
+type summaryFile struct {
+	mu       sync.Mutex
+	packages map[string]*Summary
+}
+
+// writeSummary adds the package summary to the JSON file at path and rewrites
+// it, so it always contains the summaries of all packages analyzed so far in
+// the run along with their total.
+func (s *runState) writeSummary(path string, summary *Summary) error {
+	v, _ := s.summaries.LoadOrStore(path, &summaryFile{packages: make(map[string]*Summary)})
+	f := v.(*summaryFile)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.packages[summary.Package] = summary
+
+	var out struct {
+		Packages []*Summary `json:"packages"`
+		Total    Summary    `json:"total"`
+	}
+
+	for _, pkg := range f.packages {
+		out.Packages = append(out.Packages, pkg)
+		out.Total.Tested += pkg.Tested
+		out.Total.Total += pkg.Total
+	}
+	out.Total.Ratio = Ratio(out.Total.Tested, out.Total.Total)
+
+	slices.SortFunc(out.Packages, func(a, b *Summary) int { return cmp.Compare(a.Package, b.Package) })
+
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(data, '\n'), 0o644) //nolint:gosec
+}
+
+// reportSummary writes the package summary if requested and reports packages
+// whose ratio of tested functions is below the minimum.
+func reportSummary(pass *analysis.Pass, summary *Summary) error {
+	if summaryFlag != "" {
+		if err := stateOf(pass).writeSummary(summaryFlag, summary); err != nil {
+			return err
+		}
+	}
+
+	if summary.Error == "" && summary.Ratio < minRatioFlag {
+		pass.Report(analysis.Diagnostic{
+			Pos:      pass.Files[0].Package,
+			Category: "coverage",
+			Message: fmt.Sprintf(
+				"package has tests for %.1f%% of exported functions (%d/%d), below the minimum of %.1f%%",
+				summary.Ratio*100, summary.Tested, summary.Total, minRatioFlag*100,
+			),
+		})
+	}
+
+	return nil
+}
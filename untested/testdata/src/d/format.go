@@ -0,0 +1,8 @@
+package test
+
+import "context"
+
+// Format should trigger a warning with a suggested test stub
+func (n Node) Format(ctx context.Context, _ int) string { // want "exported method \"Node.Format\" has no test"
+	return ""
+}
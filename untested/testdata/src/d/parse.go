@@ -0,0 +1,15 @@
+package test
+
+import "io"
+
+type Node struct{}
+
+// Parse should trigger a warning with a suggested test stub
+func Parse(r io.Reader, strict bool) (*Node, error) { // want "exported function \"Parse\" has no test"
+	return nil, nil
+}
+
+// MustParse should not trigger a warning (has test)
+func MustParse(r io.Reader) *Node {
+	return nil
+}
@@ -0,0 +1,10 @@
+package test
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMustParse(t *testing.T) {
+	MustParse(strings.NewReader(""))
+}
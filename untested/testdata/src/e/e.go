@@ -0,0 +1,52 @@
+package test
+
+import "fmt"
+
+type Celsius float64
+
+// String should not trigger a warning (fmt.Stringer, Celsius used by tests)
+func (c Celsius) String() string {
+	return fmt.Sprintf("%.1f°C", float64(c))
+}
+
+type ParseError struct{}
+
+// Error should not trigger a warning (error, ParseError used by tests)
+func (e *ParseError) Error() string {
+	return "parse error"
+}
+
+func Parse(s string) error {
+	return &ParseError{}
+}
+
+type Temperatures []Celsius
+
+// Len, Less and Swap should not trigger a warning (sort.Interface)
+func (t Temperatures) Len() int           { return len(t) }
+func (t Temperatures) Less(i, j int) bool { return t[i] < t[j] }
+func (t Temperatures) Swap(i, j int)      { t[i], t[j] = t[j], t[i] }
+
+// MarshalJSON should not trigger a warning (json.Marshaler)
+func (t Temperatures) MarshalJSON() ([]byte, error) {
+	return nil, nil
+}
+
+// Max should trigger a warning (not part of an interface)
+func (t Temperatures) Max() Celsius { // want "exported method \"Temperatures.Max\" has no test"
+	return 0
+}
+
+type Kelvin float64
+
+// String should trigger a warning (Kelvin not used by tests)
+func (k Kelvin) String() string { // want "exported method \"Kelvin.String\" has no test"
+	return fmt.Sprintf("%.1fK", float64(k))
+}
+
+type Fahrenheit float64
+
+// String should trigger a warning (wrong signature for fmt.Stringer)
+func (f Fahrenheit) String(precision int) string { // want "exported method \"Fahrenheit.String\" has no test"
+	return ""
+}
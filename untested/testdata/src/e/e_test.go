@@ -0,0 +1,26 @@
+package test
+
+import (
+	"errors"
+	"sort"
+	"testing"
+)
+
+func TestCelsius(t *testing.T) {
+	var f Fahrenheit
+	_ = f
+
+	c := Celsius(21.5)
+	t.Log(c)
+}
+
+func TestParse(t *testing.T) {
+	var perr *ParseError
+	if err := Parse(""); !errors.As(err, &perr) {
+		t.Fatal("expected parse error")
+	}
+}
+
+func TestTemperatures(t *testing.T) {
+	sort.Sort(Temperatures{3, 1, 2})
+}
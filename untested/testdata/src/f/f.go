@@ -0,0 +1,51 @@
+package test
+
+//untested:ignore thin wrapper around the standard library
+func IgnoredFunction() {}
+
+// IgnoredMethods is only used by integration tests.
+//
+//untested:ignore covered by integration tests
+type IgnoredMethods struct{}
+
+// Method should not trigger a warning (type directive)
+func (IgnoredMethods) Method() {}
+
+// PointerMethod should not trigger a warning (type directive)
+func (*IgnoredMethods) PointerMethod() {}
+
+//untested:ignore grouped types
+type (
+	// GroupedA should not trigger warnings (group directive)
+	GroupedA struct{}
+
+	// GroupedB should not trigger warnings (spec directive)
+	//
+	// want +1 "//untested:ignore directive does not suppress anything"
+	//untested:ignore tested below
+	GroupedB struct{}
+)
+
+func (GroupedA) Method() {}
+
+func (GroupedB) Method() {}
+
+// TestedFunction has a stale directive
+//
+// want +2 "//untested:ignore directive does not suppress anything"
+//
+//untested:ignore tested elsewhere
+func TestedFunction() {}
+
+// want +2 "//untested:ignore directive requires a reason"
+//
+//untested:ignore
+func NoReason() {}
+
+// NotADirective should trigger a warning (unknown directive)
+//
+//untested:ignored some reason
+func NotADirective() {} // want "exported function \"NotADirective\" has no test"
+
+// Untested should trigger a warning (no directive)
+func Untested() {} // want "exported function \"Untested\" has no test"
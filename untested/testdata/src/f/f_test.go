@@ -0,0 +1,12 @@
+package test
+
+import "testing"
+
+func TestTestedFunction(t *testing.T) {
+	TestedFunction()
+	IgnoredTested()
+}
+
+func TestGroupedB(t *testing.T) {
+	GroupedB{}.Method()
+}
@@ -0,0 +1,6 @@
+//untested:ignore deprecated file kept for compatibility
+
+package test
+
+// FileIgnored should not trigger a warning (file directive)
+func FileIgnored() {}
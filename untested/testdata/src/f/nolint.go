@@ -0,0 +1,24 @@
+package test
+
+// Suppressed should not trigger a warning (nolint directive)
+//
+//nolint:untested
+func Suppressed() {}
+
+// Ignored should not trigger a warning (gocheck:ignore directive)
+func Ignored() {} //gocheck:ignore untested exercised by the integration tests
+
+// Other should trigger a warning (directive for another analyzer)
+//
+//nolint:fieldorder
+func Other() {} // want "exported function \"Other\" has no test"
+
+// IgnoredNoReason should not trigger a warning (gocheck:ignore directive without a reason)
+//
+// want +1 "//gocheck:ignore directive requires a reason"
+func IgnoredNoReason() {} //gocheck:ignore untested
+
+// IgnoredTested should not trigger a warning (tested, so the directive is unused)
+//
+// want +1 "//gocheck:ignore directive does not suppress anything"
+func IgnoredTested() {} //gocheck:ignore untested tested elsewhere
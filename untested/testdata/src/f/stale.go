@@ -0,0 +1,6 @@
+// want +1 "//untested:ignore directive does not suppress anything"
+//untested:ignore nothing to ignore
+
+package test
+
+func unexported() {}
@@ -0,0 +1,4 @@
+//untested:ignore experimental package
+
+// Package test is experimental.
+package test
@@ -0,0 +1,4 @@
+package test
+
+// Experimental should not trigger a warning (package directive)
+func Experimental() {}
@@ -0,0 +1,13 @@
+package test
+
+// Default should not trigger a warning (tested by default build)
+func Default() {}
+
+// Integration should not trigger a warning (tested with integration tag)
+func Integration() {}
+
+// Windows should not trigger a warning (tested on windows)
+func Windows() {}
+
+// Untested should trigger a warning (no test in any tag set)
+func Untested() {} // want "exported function \"Untested\" has no test"
@@ -0,0 +1,7 @@
+package test
+
+import "testing"
+
+func TestDefault(t *testing.T) {
+	Default()
+}
@@ -0,0 +1,7 @@
+package test
+
+import "testing"
+
+func TestWindows(t *testing.T) {
+	Windows()
+}
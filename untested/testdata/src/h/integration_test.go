@@ -0,0 +1,9 @@
+//go:build integration
+
+package test
+
+import "testing"
+
+func TestIntegration(t *testing.T) {
+	Integration()
+}
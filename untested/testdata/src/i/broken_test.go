@@ -0,0 +1,10 @@
+//go:build broken
+
+package test
+
+import "testing"
+
+func TestBroken(t *testing.T) {
+	Broken()
+	Missing()
+}
@@ -0,0 +1,4 @@
+package test // want "could not analyze tests: .*broken_test.go:9:2: undefined: Missing"
+
+// Broken should not trigger a warning (tests could not be analyzed)
+func Broken() {}
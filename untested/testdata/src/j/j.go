@@ -0,0 +1,29 @@
+package test
+
+// Unchanged should not trigger a warning (not in diff)
+func Unchanged() {}
+
+// Added should trigger a warning (added in diff)
+func Added() {} // want "exported function \"Added\" has no test"
+
+// Modified should trigger a warning (body modified in diff)
+func Modified() int { // want "exported function \"Modified\" has no test"
+	x := 1
+	return x
+}
+
+// Deleted should trigger a warning (line deleted from body in diff)
+func Deleted() { // want "exported function \"Deleted\" has no test"
+	println()
+}
+
+// AddedWithTest should not trigger a warning (has test)
+func AddedWithTest() {}
+
+// want +2 "//untested:ignore directive does not suppress anything"
+//
+//untested:ignore added stale directive
+func StaleDirective() {}
+
+//untested:ignore unchanged stale directive
+func UnchangedStaleDirective() {}
@@ -0,0 +1,9 @@
+package test
+
+import "testing"
+
+func TestAddedWithTest(t *testing.T) {
+	AddedWithTest()
+	StaleDirective()
+	UnchangedStaleDirective()
+}
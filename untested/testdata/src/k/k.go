@@ -0,0 +1,13 @@
+package test // want "package has tests for 33.3% of exported functions \\(1/3\\), below the minimum of 50.0%"
+
+// Tested should not trigger a warning (has test)
+func Tested() {}
+
+// Untested should trigger a warning (no test)
+func Untested() {} // want "exported function \"Untested\" has no test"
+
+// AlsoUntested should trigger a warning (no test)
+func AlsoUntested() {} // want "exported function \"AlsoUntested\" has no test"
+
+//untested:ignore excluded from the summary
+func Ignored() {}
@@ -0,0 +1,7 @@
+package test
+
+import "testing"
+
+func TestTested(t *testing.T) {
+	Tested()
+}
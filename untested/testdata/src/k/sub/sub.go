@@ -0,0 +1,4 @@
+package sub
+
+// Tested should not trigger a warning (has test)
+func Tested() {}
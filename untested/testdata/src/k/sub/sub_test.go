@@ -0,0 +1,7 @@
+package sub
+
+import "testing"
+
+func TestTested(t *testing.T) {
+	Tested()
+}
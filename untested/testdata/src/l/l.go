@@ -0,0 +1,54 @@
+package main
+
+import "fmt"
+
+func main() {
+	Run()
+	Start()
+}
+
+// Start should not trigger a warning (called from non-test code)
+func Start() {}
+
+// Run should not trigger a warning (tested and used by main)
+func Run() {
+	Helper()
+}
+
+// Helper should not trigger a warning (tested through Run and used by Run)
+func Helper() {}
+
+// AssertEqual should trigger a warning (only used by tests)
+func AssertEqual(a, b int) bool { // want "exported function \"AssertEqual\" is only used by tests"
+	return a == b
+}
+
+// Old should not trigger a warning (deprecated)
+//
+// Deprecated: Use Run instead.
+func Old() {}
+
+// OldType is a deprecated type.
+//
+// Deprecated: Use Type instead.
+type OldType struct{}
+
+// Method should not trigger a warning (receiver type deprecated)
+func (OldType) Method() {}
+
+type Type struct{}
+
+// String should not trigger a warning (well-known interface)
+func (Type) String() string {
+	return fmt.Sprint("type")
+}
+
+// NotDeprecated should trigger a warning (deprecation notice not at paragraph start)
+//
+// This is not Deprecated: at all.
+func NotDeprecated() {} // want "exported function \"NotDeprecated\" has no test"
+
+// Fixture should not trigger a warning (ignored)
+//
+//untested:ignore shared fixture for tests of other packages
+func Fixture() {}
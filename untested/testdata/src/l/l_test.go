@@ -0,0 +1,12 @@
+package main
+
+import "testing"
+
+func TestRun(t *testing.T) {
+	Run()
+	if !AssertEqual(1, 1) {
+		t.Fail()
+	}
+	t.Log(Type{}.String())
+	Fixture()
+}
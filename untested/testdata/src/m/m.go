@@ -0,0 +1,31 @@
+package m
+
+// Direct should not trigger a warning (called by an invoked closure)
+func Direct() {}
+
+// Goroutine should not trigger a warning (called by a closure started in a goroutine)
+func Goroutine() {}
+
+// Deferred should not trigger a warning (called by a deferred closure)
+func Deferred() {}
+
+// Stored should not trigger a warning (called by a stored closure which is invoked)
+func Stored() {}
+
+// Passed should not trigger a warning (called by a closure passed to another function)
+func Passed() {}
+
+// Recursive should not trigger a warning (called by a recursive closure which is invoked)
+func Recursive() {}
+
+// Nested should not trigger a warning (called by a closure nested in an invoked closure)
+func Nested() {}
+
+// Unused should trigger a warning (called by a stored closure which is never invoked)
+func Unused() {} // want "exported function \"Unused\" has no test"
+
+// Discarded should trigger a warning (called by a closure assigned to the blank identifier)
+func Discarded() {} // want "exported function \"Discarded\" has no test"
+
+// Dead should trigger a warning (called by a closure nested in one which is never invoked)
+func Dead() {} // want "exported function \"Dead\" has no test"
@@ -0,0 +1,49 @@
+package m
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestClosures(t *testing.T) {
+	func() { Direct() }()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		Goroutine()
+	}()
+	wg.Wait()
+
+	defer func() { Deferred() }()
+
+	check := func() { Stored() }
+	check()
+
+	t.Run("passed", func(t *testing.T) { Passed() })
+
+	var countdown func(int)
+	countdown = func(n int) {
+		if n == 0 {
+			Recursive()
+			return
+		}
+		countdown(n - 1)
+	}
+	countdown(3)
+
+	func() {
+		func() { Nested() }()
+	}()
+
+	unused := func() { Unused() }
+	_ = unused
+
+	_ = func() { Discarded() }
+
+	outer := func() {
+		func() { Dead() }()
+	}
+	_ = outer
+}
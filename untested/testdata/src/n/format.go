@@ -0,0 +1,9 @@
+package n
+
+// Format should trigger a warning (only tested from parser_test.go)
+func Format(n Node) string { return n.Value } // want "exported function \"Format\" is not tested from format_test.go"
+
+// Print should not trigger a warning (ignored)
+//
+//untested:ignore covered by the round trip test in parser_test.go
+func Print(n Node) { println(Format(n)) }
@@ -0,0 +1,9 @@
+package n // want "format_test.go does not test anything from format.go"
+
+import "testing"
+
+func TestFormat(t *testing.T) {
+	if got := Tokenize("x"); len(got) != 1 {
+		t.Errorf("got %v", got)
+	}
+}
@@ -0,0 +1,4 @@
+package n
+
+// Lex should trigger a warning (lexer_test.go does not exist)
+func Lex(s string) []string { return []string{s} } // want "exported function \"Lex\" is not tested from lexer_test.go"
@@ -0,0 +1,10 @@
+package n
+
+// Parse should not trigger a warning (tested from parser_test.go)
+func Parse(s string) Node { return Node{Value: s} }
+
+// Tokenize should trigger a warning (only tested from format_test.go)
+func Tokenize(s string) []string { return []string{s} } // want "exported function \"Tokenize\" is not tested from parser_test.go"
+
+// Untested should trigger a warning (not tested at all)
+func Untested() {} // want "exported function \"Untested\" has no test"
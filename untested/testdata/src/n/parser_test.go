@@ -0,0 +1,11 @@
+package n
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	if got := Format(Parse("x")); got != "x" {
+		t.Errorf("got %q", got)
+	}
+	Print(Parse("x"))
+	Lex("x")
+}
@@ -0,0 +1,6 @@
+package n
+
+// Node is a parsed node.
+type Node struct {
+	Value string
+}
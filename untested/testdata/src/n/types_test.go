@@ -0,0 +1,7 @@
+package n
+
+import "testing"
+
+func TestNode(t *testing.T) {
+	_ = Node{Value: "x"}
+}
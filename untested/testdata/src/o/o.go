@@ -0,0 +1,52 @@
+package o
+
+import "errors"
+
+// Node is a parsed node.
+type Node struct{ Value string }
+
+// Parse should not trigger a warning (result and error compared)
+func Parse(s string) (Node, error) {
+	if err := Validate(s); err != nil {
+		return Node{}, err
+	}
+	return Node{Value: s}, nil
+}
+
+// Validate should not trigger a warning (called by Parse which is asserted on)
+func Validate(s string) error {
+	if s == "" {
+		return errors.New("empty")
+	}
+	return nil
+}
+
+// Discarded should trigger a warning (result discarded)
+func Discarded() string { return "" } // want "exported function \"Discarded\" has no test"
+
+// Blank should trigger a warning (result assigned to blank identifier)
+func Blank() (int, error) { return 0, nil } // want "exported function \"Blank\" has no test"
+
+// Logged should trigger a warning (result only logged)
+func Logged() string { return "" } // want "exported function \"Logged\" has no test"
+
+// Errored should not trigger a warning (error passed to t.Error)
+func Errored() error { return nil }
+
+// Equal should not trigger a warning (result passed to reflect.DeepEqual)
+func Equal() []int { return []int{1} }
+
+// Helped should not trigger a warning (result passed to a test helper)
+func Helped() int { return 1 }
+
+// Custom should not trigger a warning (result passed to a configured assertion function)
+func Custom() string { return "" }
+
+// Captured should not trigger a warning (result compared in a closure)
+func Captured() int { return 1 }
+
+// NoResult should not trigger a warning (has no results)
+func NoResult() {}
+
+// Table should not trigger a warning (used as a value)
+func Table() int { return 1 }
@@ -0,0 +1,56 @@
+package o
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	got, err := Parse("x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Value != "x" {
+		t.Errorf("got %q", got.Value)
+	}
+}
+
+func TestUnasserted(t *testing.T) {
+	Discarded()
+	_, _ = Blank()
+	t.Logf("%s", Logged())
+	NoResult()
+	_ = Errored()
+}
+
+func TestAsserted(t *testing.T) {
+	if err := Errored(); err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(Equal(), []int{1}) {
+		t.Fail()
+	}
+	assertInt(t, Helped(), 1)
+	_ = strings.EqualFold(Custom(), "")
+
+	v := Captured()
+	func() {
+		if v != 1 {
+			t.Fail()
+		}
+	}()
+
+	for _, fn := range []func() int{Table} {
+		if fn() != 1 {
+			t.Fail()
+		}
+	}
+}
+
+func assertInt(t *testing.T, got, want int) {
+	t.Helper()
+	if got != want {
+		t.Errorf("got %d, want %d", got, want)
+	}
+}
@@ -0,0 +1,31 @@
+package p
+
+// Encode should not trigger a warning (benchmarked)
+//
+//perf:critical
+func Encode(b []byte) []byte { return appendEncoded(nil, b) }
+
+// appendEncoded should not trigger a warning (reachable from a benchmark through Encode)
+//
+//perf:critical
+func appendEncoded(dst, b []byte) []byte { return append(dst, b...) }
+
+// Decode should trigger a warning (only tested)
+//
+//perf:critical
+func Decode(b []byte) []byte { return b } // want "performance-critical function \"Decode\" has no benchmark"
+
+// hash should trigger a warning (not reachable from any benchmark)
+//
+//perf:critical
+func hash(b []byte) int { return len(b) } // want "performance-critical function \"hash\" has no benchmark"
+
+type Buffer struct{ data []byte }
+
+// Write should trigger a warning (only called from a helper named like a benchmark)
+//
+//perf:critical
+func (b *Buffer) Write(p []byte) { b.data = append(b.data, p...) } // want "performance-critical method \"Buffer.Write\" has no benchmark"
+
+// Reset should not trigger a warning (not annotated)
+func (b *Buffer) Reset() { b.data = b.data[:0] }
@@ -0,0 +1,24 @@
+package p
+
+import "testing"
+
+func TestCodec(t *testing.T) {
+	if string(Decode(Encode([]byte("x")))) != "x" {
+		t.Fail()
+	}
+	var b Buffer
+	b.Write(nil)
+	b.Reset()
+	_ = hash(nil)
+}
+
+func BenchmarkEncode(b *testing.B) {
+	for b.Loop() {
+		Encode([]byte("x"))
+	}
+}
+
+func Benchmarkwrite(b *testing.B) {
+	var buf Buffer
+	buf.Write(nil)
+}
@@ -0,0 +1,11 @@
+package q
+
+var (
+	ParseInternal = parseInternal
+	ValidateFunc  = Validate
+	UnusedFunc    = Unused
+)
+
+var hook = Hidden
+
+func (s *Scanner) Pos() int { return s.pos }
@@ -0,0 +1,32 @@
+package q
+
+// Parse should not trigger a warning (tested by the external test package)
+func Parse(s string) string { return parseInternal(s) }
+
+// parseInternal is tested through an alias in export_test.go.
+func parseInternal(s string) string { return s }
+
+// Format should not trigger a warning (tested by the in-package tests)
+func Format(s string) string { return s }
+
+// Scanner scans input.
+type Scanner struct{ pos int }
+
+// Scan should not trigger a warning (tested through an exported method in export_test.go)
+func (s *Scanner) Scan() bool { return s.next() }
+
+func (s *Scanner) next() bool { s.pos++; return true }
+
+// Validate should not trigger a warning (tested through an alias used by the external tests)
+func Validate(s string) bool { return validate(s) }
+
+func validate(s string) bool { return s != "" }
+
+// Unused should trigger a warning (aliased in export_test.go but never used)
+func Unused() {} // want "exported function \"Unused\" has no test"
+
+// Untested should trigger a warning (not tested)
+func Untested() {} // want "exported function \"Untested\" has no test"
+
+// Hidden should trigger a warning (aliased by an unused variable named like one used by the external tests)
+func Hidden() {} // want "exported function \"Hidden\" has no test"
@@ -0,0 +1,26 @@
+package q_test
+
+import (
+	"testing"
+
+	"q"
+)
+
+var hook = func() {}
+
+func TestParse(t *testing.T) {
+	hook()
+
+	if q.Parse("x") != q.ParseInternal("x") {
+		t.Fail()
+	}
+	if !q.ValidateFunc("x") {
+		t.Fail()
+	}
+
+	var s q.Scanner
+	s.Scan()
+	if s.Pos() != 1 {
+		t.Fail()
+	}
+}
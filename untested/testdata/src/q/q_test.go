@@ -0,0 +1,9 @@
+package q
+
+import "testing"
+
+func TestFormat(t *testing.T) {
+	if Format("x") != "x" {
+		t.Fail()
+	}
+}
@@ -0,0 +1,8 @@
+package test
+
+// Parse should trigger a warning as no test calls it.
+func Parse() { // want "exported function \"Parse\" has no test"
+}
+
+// Format should not trigger a warning as it is tested.
+func Format() {}
@@ -0,0 +1,4 @@
+package test
+
+// MockParse should not trigger a warning as the file is excluded.
+func MockParse() {}
@@ -0,0 +1,7 @@
+package test
+
+import "testing"
+
+func TestFormat(t *testing.T) {
+	Format()
+}
@@ -0,0 +1,10 @@
+package test
+
+import "io"
+
+type Node struct{}
+
+// Walk should trigger a warning without a suggested fix, as node_test.go doesn't exist
+func (n *Node) Walk(w io.Writer, fn func(*Node) bool, depth ...int) error { // want "exported method \"Node.Walk\" has no test"
+	return nil
+}
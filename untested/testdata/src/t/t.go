@@ -0,0 +1,15 @@
+package t
+
+// Direct should not trigger a warning (called by a test)
+func Direct() { Indirect() }
+
+// Indirect should not trigger a warning (called by Direct and a test helper)
+func Indirect() {}
+
+// Orphaned should trigger a warning (only called by an unused test helper)
+func Orphaned() {} // want "exported function \"Orphaned\" has no test"
+
+// Ignored should not trigger a warning (ignored)
+//
+//untested:ignore covered by integration tests
+func Ignored() {}
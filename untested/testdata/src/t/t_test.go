@@ -0,0 +1,11 @@
+package t
+
+import "testing"
+
+func TestDirect(t *testing.T) { Direct() }
+
+func TestHelper(t *testing.T) { helper() }
+
+func helper() { Indirect() }
+
+func unused() { Orphaned() }
@@ -2,36 +2,75 @@
 package untested
 
 import (
+	"fmt"
 	"go/ast"
+	"go/token"
 	"go/types"
 	"path/filepath"
+	"reflect"
+	"slices"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"golang.org/x/tools/go/analysis"
 	"golang.org/x/tools/go/analysis/passes/inspect"
 	"golang.org/x/tools/go/ast/inspector"
 	"golang.org/x/tools/go/packages"
 
+	"github.com/abemedia/gocheck/internal/refgraph"
 	"github.com/abemedia/gocheck/internal/skip"
 )
 
 var (
-	internalFlag  = false
-	generatedFlag = false
+	internalFlag    = false
+	generatedFlag   = false
+	interfacesFlag  = defaultInterfaces
+	tagsFlag        = ""
+	failfastFlag    = false
+	sinceFlag       = ""
+	diffFlag        = ""
+	summaryFlag     = ""
+	minRatioFlag    = 0.0
+	deprecatedFlag  = true
+	testOnlyFlag    = false
+	localityFlag    = false
+	assertFlag      = false
+	assertFuncsFlag = defaultAssertFuncs
+	excludeFlag     skip.Exclude
 )
 
 // NewAnalyzer returns an analyzer that reports exported functions and methods
 // without corresponding tests.
 func NewAnalyzer() *analysis.Analyzer {
 	analyzer := &analysis.Analyzer{
-		Name:     "untested",
-		Doc:      "check that exported functions and methods have tests",
-		Run:      run,
-		Requires: []*analysis.Analyzer{inspect.Analyzer},
+		Name:       "untested",
+		Doc:        "check that exported functions and methods have tests",
+		Run:        run,
+		Requires:   []*analysis.Analyzer{inspect.Analyzer},
+		ResultType: reflect.TypeFor[*Result](),
 	}
 
 	analyzer.Flags.BoolVar(&internalFlag, "internal", false, "check functions in internal packages")
 	analyzer.Flags.BoolVar(&generatedFlag, "generated", false, "check functions in generated files")
+	analyzer.Flags.StringVar(&interfacesFlag, "interfaces", defaultInterfaces,
+		"comma-separated list of interfaces whose methods are covered when their receiver type is used by tests")
+	analyzer.Flags.StringVar(&tagsFlag, "tags", "",
+		"semicolon-separated list of comma-separated build tag sets whose tests also count, e.g. 'integration;GOOS=windows'")
+	analyzer.Flags.BoolVar(&failfastFlag, "failfast", false, "fail instead of reporting errors loading tests")
+	analyzer.Flags.StringVar(&sinceFlag, "since", "", "only check functions changed relative to the given git revision")
+	analyzer.Flags.StringVar(&diffFlag, "diff", "", "only check functions changed in the given unified diff file")
+	analyzer.Flags.StringVar(&summaryFlag, "summary", "", "write a JSON summary of tested functions per package to the given file")
+	analyzer.Flags.Float64Var(&minRatioFlag, "min-ratio", 0, "minimum ratio of tested exported functions per package")
+	analyzer.Flags.BoolVar(&deprecatedFlag, "deprecated", true, "check deprecated functions")
+	analyzer.Flags.BoolVar(&testOnlyFlag, "test-only", false, "report exported functions only used by tests")
+	analyzer.Flags.BoolVar(&localityFlag, "locality", false,
+		"report functions in foo.go not tested from foo_test.go and test files not testing their source file")
+	analyzer.Flags.BoolVar(&assertFlag, "assert", false, "only count calls from tests whose results are asserted on")
+	analyzer.Flags.StringVar(&assertFuncsFlag, "assert-funcs", defaultAssertFuncs,
+		"comma-separated list of functions, or packages, asserting on their arguments in assert mode")
+	excludeFlag = skip.Exclude{}
+	analyzer.Flags.Var(&excludeFlag, "exclude", "comma-separated list of file globs, re:, pkg: or marker: patterns to skip")
 
 	return analyzer
 }
@@ -40,208 +79,404 @@ func NewAnalyzer() *analysis.Analyzer {
 // It builds a call graph from test files and checks which exported functions
 // are not referenced directly or transitively from any test.
 func run(pass *analysis.Pass) (any, error) {
+	result := &Result{Package: pass.Pkg.Path()}
 	if !internalFlag && isInternalPackage(pass.Pkg.Path()) {
-		return nil, nil
+		return result, nil
 	}
 
 	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
-	var exportedFunctions []*ast.FuncDecl
+	var exportedFunctions, criticalFunctions []*ast.FuncDecl
 
 	// Create skip filter using the optimized helper
-	shouldSkipNode := skip.NewFileStrategy(pass, func(file *ast.File) bool {
-		filename := pass.Fset.Position(file.Pos()).Filename
-		return strings.HasSuffix(filename, "_test.go") || (!generatedFlag && ast.IsGenerated(file))
-	})
+	shouldSkipNode := skip.Any(
+		skip.NewFileStrategy(pass, func(file *ast.File) bool {
+			filename := pass.Fset.Position(file.Pos()).Filename
+			return strings.HasSuffix(filename, "_test.go") || (!generatedFlag && ast.IsGenerated(file))
+		}),
+		skip.NewExcludeStrategy(pass, &excludeFlag),
+	)
+
+	dir := filepath.Dir(pass.Fset.Position(pass.Files[0].Pos()).Filename)
+
+	// In diff-aware mode only report functions overlapping a changed line
+	isChanged := func(ast.Node) bool { return true }
+	if sinceFlag != "" || diffFlag != "" {
+		changes, err := stateOf(pass).loadChanges(dir)
+		if err != nil {
+			return nil, err
+		}
+		if isChanged, err = changes.filter(pass); err != nil {
+			return nil, err
+		}
+	}
+
+	dirs := parseDirectives(pass, shouldSkipNode)
+	directives := skip.NewDirectives(pass)
+	reportDirectives := func() {
+		dirs.report(pass, isChanged)
+		directives.Report(skip.All(skip.Not(shouldSkipNode), isChanged))
+	}
+
+	var deprecatedTypes map[string]bool
+	if !deprecatedFlag {
+		deprecatedTypes = collectDeprecatedTypes(pass)
+	}
 
 	nodeFilter := []ast.Node{(*ast.FuncDecl)(nil)}
 	inspect.Preorder(nodeFilter, func(n ast.Node) {
 		funcDecl := n.(*ast.FuncDecl)
-		if funcDecl.Name.IsExported() && !shouldSkipNode(funcDecl) {
-			exportedFunctions = append(exportedFunctions, funcDecl)
+		if shouldSkipNode(funcDecl) || !isChanged(funcDecl) {
+			return
+		}
+		if isPerfCritical(funcDecl.Doc) {
+			criticalFunctions = append(criticalFunctions, funcDecl)
 		}
+		if !funcDecl.Name.IsExported() {
+			return
+		}
+		if !deprecatedFlag {
+			typeName, _, isMethod := strings.Cut(getFuncDeclName(funcDecl), ".")
+			if isDeprecated(funcDecl.Doc) || (isMethod && deprecatedTypes[typeName]) {
+				return
+			}
+		}
+		exportedFunctions = append(exportedFunctions, funcDecl)
 	})
 
-	// If no exported functions, nothing to check
-	if len(exportedFunctions) == 0 {
-		return nil, nil
+	// If no exported functions, nothing to check unless test files are checked
+	// for locality too
+	if len(exportedFunctions) == 0 && len(criticalFunctions) == 0 && !localityFlag {
+		reportDirectives()
+		if !isTestPackage(pass) {
+			if err := reportSummary(pass, NewSummary(pass.Pkg.Path(), 0, nil)); err != nil {
+				return nil, err
+			}
+		}
+		return result, nil
 	}
 
 	// Load packages with tests to find test references
-	cfg := &packages.Config{
-		Mode:  packages.LoadSyntax,
-		Dir:   filepath.Dir(pass.Fset.Position(pass.Files[0].Pos()).Filename),
-		Tests: true,
-	}
-	pkgs, err := packages.Load(cfg, ".")
+	pkgs, err := loadTestPackages(dir)
 	if err != nil {
 		return nil, err
 	}
 
-	testReferences := make(map[string]bool)
+	// Without complete test information every function would appear untested,
+	// so report why the tests could not be analyzed instead.
+	if errs := loadErrors(pkgs); len(errs) > 0 {
+		if failfastFlag {
+			return nil, fmt.Errorf("could not analyze tests: %w", errs[0])
+		}
+		reportLoadErrors(pass, errs)
+		result.Error = fmt.Sprintf("could not analyze tests: %v", errs[0])
+		if err := reportSummary(pass, &Summary{Package: pass.Pkg.Path(), Error: result.Error}); err != nil {
+			return nil, err
+		}
+		return result, nil
+	}
+
+	isTarget := targetPackages(pkgs, pass.Pkg)
+	refs := collectTestReferences(pkgs, isTarget)
+
+	testTypes := make(map[string]bool)
 
 	for _, pkg := range pkgs {
 		for _, file := range pkg.Syntax {
-			filename := pkg.Fset.Position(file.Pos()).Filename
-			if strings.HasSuffix(filename, "_test.go") {
-				collectTestReferences(file, pkg, pass.Pkg, testReferences)
+			if isTestFile(pkg.Fset, file) {
+				collectTestTypes(file, pkg, isTarget, testTypes)
 			}
 		}
 	}
 
+	ifaces := parseInterfaces(pass.Pkg, interfacesFlag)
+
+	// suppressed determines if diagnostics at the function are suppressed.
+	suppressed := func(funcDecl *ast.FuncDecl) bool {
+		if dirs.lookup(pass, funcDecl) != nil {
+			return true
+		}
+		_, ok := directives.Suppressed(funcDecl)
+		return ok
+	}
+
 	// Check each exported function for tests
+	total := len(exportedFunctions)
+	var untested []string
+
 	for _, funcDecl := range exportedFunctions {
-		if key := getFuncDeclName(funcDecl); !testReferences[key] {
-			pass.ReportRangef(funcDecl, "exported %s %q has no test", getFuncType(funcDecl), key)
+		key, ref := getFuncDeclName(funcDecl), refs.key(funcDecl)
+
+		fn := &Function{
+			Name:     key,
+			Kind:     getFuncType(funcDecl),
+			Position: pass.Fset.Position(funcDecl.Pos()).String(),
+			Tested:   refs.tested[ref],
+			Tests:    refs.tests[ref],
+			Depth:    refs.depth[ref],
 		}
+		result.Functions = append(result.Functions, fn)
+
+		// Exported functions only called from tests are likely test helpers
+		// leaking into the package's API. Methods of well-known interfaces are
+		// usually called dynamically, so they are not considered.
+		if testOnlyFlag && refs.tested[ref] && !refs.used[ref] {
+			if _, ok := implementsInterface(pass, funcDecl, ifaces); !ok && !suppressed(funcDecl) {
+				pass.ReportRangef(funcDecl, "exported %s %q is only used by tests", getFuncType(funcDecl), key)
+			}
+		}
+
+		// Functions should be tested from their source file's counterpart.
+		if localityFlag && refs.tested[ref] {
+			testFile := testFileFor(pass.Fset.Position(funcDecl.Pos()).Filename)
+			if !refs.files[testFile][ref] && !suppressed(funcDecl) {
+				pass.ReportRangef(funcDecl, "exported %s %q is not tested from %s",
+					getFuncType(funcDecl), key, filepath.Base(testFile))
+			}
+		}
+
+		if !refs.tested[ref] {
+			// Methods only called through well-known interfaces are covered
+			// if their receiver type is used by tests.
+			if recv, ok := implementsInterface(pass, funcDecl, ifaces); ok && testTypes[recv] {
+				fn.Tested = true
+				continue
+			}
+
+			// Suppressed functions don't count towards the summary.
+			if dir := dirs.lookup(pass, funcDecl); dir != nil {
+				fn.Ignored = dir.reason
+				total--
+				continue
+			}
+			if reason, ok := directives.Suppressed(funcDecl); ok {
+				fn.Ignored = reason
+				total--
+				continue
+			}
+
+			untested = append(untested, key)
+			pass.Report(analysis.Diagnostic{
+				Pos:            funcDecl.Pos(),
+				End:            funcDecl.End(),
+				Message:        fmt.Sprintf("exported %s %q has no test", getFuncType(funcDecl), key),
+				SuggestedFixes: suggestTestFix(pass, funcDecl),
+			})
+		}
+	}
+
+	reportUnbenchmarked(pass, criticalFunctions, refs, directives)
+
+	if localityFlag {
+		reportUntestedFiles(pass, shouldSkipNode, isChanged, refs)
 	}
 
-	return nil, nil
+	reportDirectives()
+
+	if err := reportSummary(pass, NewSummary(pass.Pkg.Path(), total, untested)); err != nil {
+		return nil, err
+	}
+
+	return result, nil
 }
 
-// collectTestReferences builds a call graph from the given test file and all
-// non-test files in the package, then propagates test coverage transitively.
-// It marks exported functions as tested if they are called directly from tests
-// or indirectly through helper functions.
-func collectTestReferences(
-	file *ast.File,
-	pkg *packages.Package,
-	targetPkg *types.Package,
-	testReferences map[string]bool,
-) {
-	// Build a simple call graph using AST analysis
-	callGraph := make(map[string][]string)
-	ast.Inspect(file, func(n ast.Node) bool {
-		if node, ok := n.(*ast.FuncDecl); ok && node.Name != nil {
-			funcName := node.Name.Name
-			collectCallsFromFunction(node, pkg, targetPkg, callGraph, testReferences, funcName)
+// references holds the functions referenced by the tests of a package, keyed
+// by their names qualified by the package path.
+type references struct {
+	pkgPath     string // path of the package as loaded with its tests
+	tested      map[string]bool
+	used        map[string]bool
+	benchmarked map[string]bool
+	files       map[string]map[string]bool // by test filename, in locality mode
+	tests       map[string][]string        // test functions covering each function
+	depth       map[string]int             // number of calls from the nearest test
+}
+
+// key returns the key of a function declared in the package. The path of the
+// package loaded with its tests is used, which may differ from the analyzed
+// package's, e.g. in analysistest.
+func (r *references) key(funcDecl *ast.FuncDecl) string {
+	return r.pkgPath + "." + getFuncDeclName(funcDecl)
+}
+
+// collectTestReferences builds a graph of the references made in the files of
+// the package and its tests, including external tests, then propagates test
+// coverage transitively. It marks exported functions as tested if they are
+// referenced directly from tests or indirectly through helper functions and
+// aliases in export_test.go, as used if they are referenced from non-test code,
+// and as benchmarked if they are reachable from benchmarks. In locality mode,
+// the functions reachable from each test file are collected too.
+func collectTestReferences(pkgs []*packages.Package, isTarget func(*types.Package) bool) *references {
+	refs := &references{
+		tested:      make(map[string]bool),
+		used:        make(map[string]bool),
+		benchmarked: make(map[string]bool),
+		files:       make(map[string]map[string]bool),
+		tests:       make(map[string][]string),
+		depth:       make(map[string]int),
+	}
+	if len(pkgs) == 0 {
+		return refs
+	}
+
+	for _, pkg := range pkgs {
+		if isTarget(pkg.Types) {
+			refs.pkgPath = pkg.PkgPath
+			break
 		}
-		return true
-	})
+	}
 
-	// Also collect calls from target package functions (for transitive calls)
-	// Need to check all files in the target package, not just the test file
-	for _, targetFile := range pkg.Syntax {
-		targetFilename := pkg.Fset.Position(targetFile.Pos()).Filename
-		if !strings.HasSuffix(targetFilename, "_test.go") {
-			ast.Inspect(targetFile, func(n ast.Node) bool {
-				if funcDecl, ok := n.(*ast.FuncDecl); ok {
-					if funcDecl.Name != nil {
-						funcName := funcDecl.Name.Name
-						collectCallsFromFunction(funcDecl, pkg, targetPkg, callGraph, testReferences, funcName)
-					}
-				}
-				return true
-			})
+	graph := refgraph.New()
+	for _, pkg := range pkgs {
+		if isTestMain(pkg.Types) {
+			continue // calls all tests from a non-test file
 		}
+		graph.Add(pkg.Syntax, pkg.TypesInfo, func(obj types.Object) bool {
+			return obj.Pkg() != nil && (isTarget(obj.Pkg()) || obj.Pkg() == pkg.Types)
+		})
 	}
 
-	propagateTestCoverage(callGraph, testReferences)
-}
+	fset := pkgs[0].Fset
 
-// propagateTestCoverage performs transitive closure on the call graph to mark
-// all functions reachable from test functions as tested. It iteratively
-// propagates test coverage: if a function is called by a test or an already
-// tested function, it gets marked as tested too.
-func propagateTestCoverage(callGraph map[string][]string, testReferences map[string]bool) {
-	changed := true
-	for changed {
-		changed = false
-
-		for caller, callees := range callGraph {
-			if isTestFunction(caller) || testReferences[caller] {
-				for _, callee := range callees {
-					if !testReferences[callee] {
-						testReferences[callee] = true
-						changed = true
-					}
-				}
+	var roots, usedRoots []string
+	for _, pkg := range pkgs {
+		if isTestMain(pkg.Types) {
+			continue
+		}
+		for _, file := range pkg.Syntax {
+			if !isTestFile(fset, file) {
+				usedRoots = append(usedRoots, graph.FileReferences(file)...)
+				continue
 			}
+			roots = append(roots, graph.FileReferences(file)...)
 		}
 	}
-}
 
-// collectCallsFromFunction analyzes a function declaration to find all function
-// calls within it, building the call graph and marking exported functions as tested.
-func collectCallsFromFunction(
-	funcDecl *ast.FuncDecl,
-	pkg *packages.Package,
-	targetPkg *types.Package,
-	callGraph map[string][]string,
-	testReferences map[string]bool,
-	funcName string,
-) {
-	ast.Inspect(funcDecl, func(n ast.Node) bool {
-		call, ok := n.(*ast.CallExpr)
-		if !ok {
-			return true
+	// Only the functions run by go test are roots, so unused helpers in test
+	// files don't count and depths are measured from the tests.
+	var tests, benchmarks []string
+	for key, funcDecl := range graph.Decls {
+		if isTestFile(fset, funcDecl) {
+			if isTestFunction(funcDecl, "Test", "Benchmark", "Fuzz", "Example") {
+				roots = append(roots, key)
+				tests = append(tests, key)
+			} else if funcDecl.Recv == nil && funcDecl.Name.Name == "init" {
+				roots = append(roots, key)
+			}
+			if isBenchmark(funcDecl) {
+				benchmarks = append(benchmarks, key)
+			}
+		} else {
+			usedRoots = append(usedRoots, graph.Callees(key)...)
 		}
+	}
 
-		var ident *ast.Ident
-		switch e := call.Fun.(type) {
-		case *ast.Ident:
-			ident = e
-		case *ast.SelectorExpr:
-			ident = e.Sel
-		default:
-			return true
+	// Variables declared in tests, such as aliases in export_test.go, are only
+	// followed if used.
+	for key, spec := range graph.Vars {
+		if !isTestFile(fset, spec) {
+			usedRoots = append(usedRoots, graph.Callees(key)...)
 		}
+	}
 
-		obj := pkg.TypesInfo.ObjectOf(ident)
-		if obj == nil {
-			return true
-		}
+	// Functions referenced from non-test code are considered covered by the
+	// tests of their callers.
+	markReferences(refs.used, usedRoots)
+	for key := range graph.Reachable(usedRoots) {
+		refs.tested[key] = true
+	}
 
-		fn, ok := obj.(*types.Func)
-		if !ok {
-			return true
+	// In assert mode only calls whose results are asserted on count, which
+	// are a call away from the test.
+	offset := 0
+	var asserted map[string][]string
+	if assertFlag {
+		roots, offset = nil, 1
+		asserted = make(map[string][]string)
+		for _, pkg := range pkgs {
+			for caller, keys := range collectAssertedReferences(pkg, isTarget) {
+				asserted[caller] = append(asserted[caller], keys...)
+				roots = append(roots, keys...)
+			}
 		}
+	}
+
+	for key, depth := range graph.Distances(roots) {
+		refs.tested[key] = true
+		refs.depth[key] = depth + offset
+	}
+	for key := range graph.Reachable(benchmarks) {
+		refs.benchmarked[key] = true
+	}
 
-		switch {
-		case fn.Pkg() != nil && fn.Pkg().Name() == targetPkg.Name():
-			if fn.Exported() {
-				key := getFuncTypeName(fn)
-				testReferences[key] = true
-				if funcName != "" {
-					callGraph[funcName] = append(callGraph[funcName], key)
-				}
-			} else if funcName != "" {
-				callGraph[funcName] = append(callGraph[funcName], fn.Name())
+	// A test covers the functions it reaches, or in assert mode those reached
+	// from the calls asserted on by the test and its helpers.
+	slices.Sort(tests)
+	for _, test := range tests {
+		covered := graph.Reachable([]string{test})
+		if assertFlag {
+			var roots []string
+			for caller := range covered {
+				roots = append(roots, asserted[caller]...)
 			}
-		case fn.Pkg() == nil || fn.Pkg().Name() == pkg.Types.Name():
-			if funcName != "" {
-				callGraph[funcName] = append(callGraph[funcName], fn.Name())
+			covered = graph.Reachable(roots)
+		}
+		for key := range covered {
+			if refs.tested[key] {
+				refs.tests[key] = append(refs.tests[key], graph.Decls[test].Name.Name)
 			}
 		}
+	}
 
-		return true
-	})
-}
+	if localityFlag {
+		collectFileReferences(pkgs, graph, refs.files)
+	}
 
-// isTestFunction determines if a function name represents a test, benchmark, or example function.
-func isTestFunction(name string) bool {
-	return strings.HasPrefix(name, "Test") || strings.HasPrefix(name, "Benchmark") || strings.HasPrefix(name, "Example")
+	return refs
 }
 
-// getFuncTypeName returns the qualified name of a function from types info,
-// including the receiver type for methods (e.g., "Type.Method" or "Function").
-func getFuncTypeName(fn *types.Func) string {
-	sig := fn.Type().(*types.Signature)
+// targetPackages returns a function determining if a package is the analyzed
+// package, i.e. one of the loaded variants with or without tests. External
+// test packages, whose name has a "_test" suffix, are not.
+func targetPackages(pkgs []*packages.Package, targetPkg *types.Package) func(*types.Package) bool {
+	paths := make(map[string]bool)
+	for _, pkg := range pkgs {
+		if pkg.Name == targetPkg.Name() {
+			paths[pkg.PkgPath] = true
+		}
+	}
+
+	return func(pkg *types.Package) bool {
+		return paths[pkg.Path()]
+	}
+}
 
-	recv := sig.Recv()
-	if recv == nil {
-		return fn.Name()
+// isTestFunction determines if a function is run by go test, i.e. its name is
+// one of the prefixes optionally followed by a non-lowercase character.
+func isTestFunction(funcDecl *ast.FuncDecl, prefixes ...string) bool {
+	if funcDecl.Recv != nil {
+		return false
 	}
 
-	recvType := recv.Type()
-	if ptr, ok := recvType.(*types.Pointer); ok {
-		recvType = ptr.Elem()
+	for _, prefix := range prefixes {
+		if rest, ok := strings.CutPrefix(funcDecl.Name.Name, prefix); ok {
+			r, _ := utf8.DecodeRuneInString(rest)
+			return !unicode.IsLower(r)
+		}
 	}
 
-	if named, ok := recvType.(*types.Named); ok {
-		return named.Obj().Name() + "." + fn.Name()
+	return false
+}
+
+// markReferences marks the given functions as referenced.
+func markReferences(references map[string]bool, keys []string) {
+	for _, key := range keys {
+		references[key] = true
 	}
+}
 
-	return recvType.String() + "." + fn.Name()
+// isTestFile determines if the node is located in a test file.
+func isTestFile(fset *token.FileSet, node ast.Node) bool {
+	return strings.HasSuffix(fset.Position(node.Pos()).Filename, "_test.go")
 }
 
 // getFuncDeclName returns the qualified name of a function from AST declaration,
@@ -281,3 +516,23 @@ func isInternalPackage(pkgPath string) bool {
 
 	return false
 }
+
+// isTestMain determines if the package is the main package generated by go
+// test.
+func isTestMain(pkg *types.Package) bool {
+	return pkg.Name() == "main" && strings.HasSuffix(pkg.Path(), ".test")
+}
+
+// isTestPackage determines if the package only consists of tests, such as an
+// external test package or the generated test main package.
+func isTestPackage(pass *analysis.Pass) bool {
+	if isTestMain(pass.Pkg) {
+		return true
+	}
+	for _, file := range pass.Files {
+		if !isTestFile(pass.Fset, file) {
+			return false
+		}
+	}
+	return true
+}
@@ -1,10 +1,21 @@
 package untested_test
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
 
+	"golang.org/x/tools/go/analysis"
 	"golang.org/x/tools/go/analysis/analysistest"
 
+	"github.com/abemedia/gocheck/check"
 	"github.com/abemedia/gocheck/untested"
 )
 
@@ -29,3 +40,330 @@ func TestUntestedWithInternal(t *testing.T) {
 	testdata := analysistest.TestData()
 	analysistest.Run(t, testdata, analyzer, "c/...")
 }
+
+func TestUntestedSuggestedFixes(t *testing.T) {
+	testdata := analysistest.TestData()
+	analysistest.RunWithSuggestedFixes(t, testdata, untested.NewAnalyzer(), "d/...")
+}
+
+func TestUntestedSuggestedFixesNewFile(t *testing.T) {
+	testdata := analysistest.TestData()
+	results := analysistest.Run(t, testdata, untested.NewAnalyzer(), "s")
+
+	diags := results[0].Diagnostics
+	if len(diags) != 1 || len(diags[0].SuggestedFixes) != 0 {
+		t.Fatalf("got diagnostics %+v, want one without suggested fixes", diags)
+	}
+}
+
+func TestUntestedInterfaces(t *testing.T) {
+	testdata := analysistest.TestData()
+	analysistest.Run(t, testdata, untested.NewAnalyzer(), "e/...")
+}
+
+func TestUntestedDirectives(t *testing.T) {
+	testdata := analysistest.TestData()
+	analysistest.Run(t, testdata, untested.NewAnalyzer(), "f/...", "g/...")
+}
+
+func TestUntestedWithExclude(t *testing.T) {
+	analyzer := untested.NewAnalyzer()
+	analyzer.Flags.Set("exclude", "*_mock.go")
+
+	testdata := analysistest.TestData()
+	analysistest.Run(t, testdata, analyzer, "r/...")
+}
+
+func TestUntestedWithTags(t *testing.T) {
+	analyzer := untested.NewAnalyzer()
+	analyzer.Flags.Set("tags", "integration;GOOS=windows")
+
+	testdata := analysistest.TestData()
+	analysistest.Run(t, testdata, analyzer, "h/...")
+}
+
+func TestUntestedWithLoadErrors(t *testing.T) {
+	analyzer := untested.NewAnalyzer()
+	analyzer.Flags.Set("tags", "broken")
+
+	testdata := analysistest.TestData()
+	analysistest.Run(t, testdata, analyzer, "i/...")
+}
+
+func TestUntestedSummaryWithLoadErrors(t *testing.T) {
+	summary := filepath.Join(t.TempDir(), "summary.json")
+
+	analyzer := untested.NewAnalyzer()
+	analyzer.Flags.Set("tags", "broken")
+	analyzer.Flags.Set("summary", summary)
+
+	testdata := analysistest.TestData()
+	analysistest.Run(t, testdata, analyzer, "i/...")
+
+	data, err := os.ReadFile(summary)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got struct {
+		Packages []untested.Summary `json:"packages"`
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got.Packages) != 1 || got.Packages[0].Package != "i" || !strings.HasPrefix(got.Packages[0].Error, "could not analyze tests: ") {
+		t.Errorf("packages = %+v, want an error for package i", got.Packages)
+	}
+}
+
+func TestUntestedWithFailFast(t *testing.T) {
+	analyzer := untested.NewAnalyzer()
+	analyzer.Flags.Set("tags", "broken")
+	analyzer.Flags.Set("failfast", "true")
+
+	var rec errorRecorder
+	testdata := analysistest.TestData()
+	analysistest.Run(&rec, testdata, analyzer, "i/...")
+
+	if !slices.ContainsFunc(rec.errors, func(err string) bool {
+		return strings.Contains(err, "could not analyze tests") && strings.Contains(err, "undefined: Missing")
+	}) {
+		t.Errorf("expected analysis to fail with load error, got %q", rec.errors)
+	}
+}
+
+func TestUntestedWithDiff(t *testing.T) {
+	analyzer := untested.NewAnalyzer()
+	analyzer.Flags.Set("diff", "testdata/src/j/changes.diff")
+
+	testdata := analysistest.TestData()
+	analysistest.Run(t, testdata, analyzer, "j/...")
+}
+
+func TestUntestedWithSince(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+
+	dir := t.TempDir()
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com", "GIT_CONFIG_GLOBAL=/dev/null")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v: %s", strings.Join(args, " "), err, out)
+		}
+	}
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("go.mod", "module example.com/a\n\ngo 1.22\n")
+	write("a.go", "package a\n\nfunc A() {}\n")
+	run("init", "-q", "-b", "master")
+	run("add", "-A")
+	run("commit", "-qm", "initial")
+
+	run("checkout", "-qb", "feature")
+	write("b.go", "package a\n\nfunc B() {}\n")
+	run("add", "-A")
+	run("commit", "-qm", "add B")
+
+	// Changes to master after branching off must not count as changes on the
+	// branch.
+	run("checkout", "-q", "master")
+	write("a.go", "package a\n\nfunc A() { println() }\n")
+	run("commit", "-qam", "change A")
+	run("checkout", "-q", "feature")
+
+	analyzer := untested.NewAnalyzer()
+	analyzer.Flags.Set("since", "master")
+
+	results, err := check.Run(context.Background(), []string{"./..."}, check.Options{
+		Analyzers: []*analysis.Analyzer{analyzer},
+		Dir:       dir,
+		Tests:     true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, r := range results {
+		got = append(got, r.Message)
+	}
+	if want := []string{`exported function "B" has no test`}; !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestUntestedResult(t *testing.T) {
+	testdata := analysistest.TestData()
+	results := analysistest.Run(t, testdata, untested.NewAnalyzer(), "t")
+
+	var got []*untested.Function
+	for _, r := range results {
+		if result, ok := r.Result.(*untested.Result); ok && len(result.Functions) > 0 {
+			got = result.Functions
+			break
+		}
+	}
+
+	want := []*untested.Function{
+		{Name: "Direct", Kind: "function", Position: "t.go:4:1", Tested: true, Tests: []string{"TestDirect"}, Depth: 1},
+		{Name: "Indirect", Kind: "function", Position: "t.go:7:1", Tested: true, Tests: []string{"TestDirect", "TestHelper"}, Depth: 2},
+		{Name: "Orphaned", Kind: "function", Position: "t.go:10:1"},
+		{Name: "Ignored", Kind: "function", Position: "t.go:15:1", Ignored: "covered by integration tests"},
+	}
+	for _, fn := range got {
+		fn.Position = filepath.Base(fn.Position)
+	}
+	if !reflect.DeepEqual(got, want) {
+		gotJSON, _ := json.MarshalIndent(got, "", "  ")
+		wantJSON, _ := json.MarshalIndent(want, "", "  ")
+		t.Errorf("got functions:\n%s\nwant:\n%s", gotJSON, wantJSON)
+	}
+}
+
+func TestUntestedSummary(t *testing.T) {
+	summary := filepath.Join(t.TempDir(), "summary.json")
+
+	analyzer := untested.NewAnalyzer()
+	analyzer.Flags.Set("summary", summary)
+	analyzer.Flags.Set("min-ratio", "0.5")
+
+	testdata := analysistest.TestData()
+	analysistest.Run(t, testdata, analyzer, "k/...")
+
+	data, err := os.ReadFile(summary)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got struct {
+		Packages []untested.Summary `json:"packages"`
+		Total    untested.Summary   `json:"total"`
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []untested.Summary{
+		{Package: "k", Tested: 1, Total: 3, Ratio: 1.0 / 3, Untested: []string{"Untested", "AlsoUntested"}},
+		{Package: "k/none", Ratio: 1},
+		{Package: "k/sub", Tested: 1, Total: 1, Ratio: 1},
+	}
+	if !reflect.DeepEqual(got.Packages, want) {
+		t.Errorf("packages = %+v, want %+v", got.Packages, want)
+	}
+	if wantTotal := (untested.Summary{Tested: 2, Total: 4, Ratio: 0.5}); !reflect.DeepEqual(got.Total, wantTotal) {
+		t.Errorf("total = %+v, want %+v", got.Total, wantTotal)
+	}
+}
+
+func TestUntestedSummaryPerRun(t *testing.T) {
+	summary := filepath.Join(t.TempDir(), "summary.json")
+
+	analyzer := untested.NewAnalyzer()
+	analyzer.Flags.Set("summary", summary)
+	analyzer.Flags.Set("min-ratio", "0.5")
+
+	testdata := analysistest.TestData()
+	analysistest.Run(t, testdata, analyzer, "k/...")
+	analysistest.Run(t, testdata, analyzer, "k/sub")
+
+	data, err := os.ReadFile(summary)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got struct {
+		Packages []untested.Summary `json:"packages"`
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	// The second run rewrites the summary rather than adding to the first.
+	want := []untested.Summary{{Package: "k/sub", Tested: 1, Total: 1, Ratio: 1}}
+	if !reflect.DeepEqual(got.Packages, want) {
+		t.Errorf("packages = %+v, want %+v", got.Packages, want)
+	}
+}
+
+func TestUntestedDeprecatedAndTestOnly(t *testing.T) {
+	analyzer := untested.NewAnalyzer()
+	analyzer.Flags.Set("deprecated", "false")
+	analyzer.Flags.Set("test-only", "true")
+
+	testdata := analysistest.TestData()
+	analysistest.Run(t, testdata, analyzer, "l/...")
+}
+
+func TestUntestedClosures(t *testing.T) {
+	testdata := analysistest.TestData()
+	analysistest.Run(t, testdata, untested.NewAnalyzer(), "m/...")
+}
+
+func TestUntestedWithLocality(t *testing.T) {
+	analyzer := untested.NewAnalyzer()
+	analyzer.Flags.Set("locality", "true")
+
+	testdata := analysistest.TestData()
+	analysistest.Run(t, testdata, analyzer, "n/...")
+}
+
+func TestUntestedWithAssert(t *testing.T) {
+	analyzer := untested.NewAnalyzer()
+	analyzer.Flags.Set("assert", "true")
+	analyzer.Flags.Set("assert-funcs", "reflect.DeepEqual,strings.EqualFold")
+
+	testdata := analysistest.TestData()
+	results := analysistest.Run(t, testdata, analyzer, "o")
+
+	// Only tests asserting on the results of calls cover the functions.
+	tests := make(map[string][]string)
+	for _, fn := range results[0].Result.(*untested.Result).Functions {
+		tests[fn.Name] = fn.Tests
+	}
+	want := map[string][]string{
+		"Parse":    {"TestParse"},
+		"Validate": {"TestParse"},
+		"Errored":  {"TestAsserted"},
+		"NoResult": {"TestUnasserted"},
+	}
+	for name, want := range want {
+		if got := tests[name]; !reflect.DeepEqual(got, want) {
+			t.Errorf("%s tests = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestUntestedPerfCritical(t *testing.T) {
+	testdata := analysistest.TestData()
+	analysistest.Run(t, testdata, untested.NewAnalyzer(), "p/...")
+}
+
+func TestUntestedExternalTests(t *testing.T) {
+	testdata := analysistest.TestData()
+
+	// Tests are loaded from the environment, so resolve the external test's
+	// import of q from testdata like analysistest.
+	t.Setenv("GOPATH", testdata)
+	t.Setenv("GO111MODULE", "off")
+
+	analysistest.Run(t, testdata, untested.NewAnalyzer(), "q/...")
+}
+
+// errorRecorder records errors reported by analysistest.
+type errorRecorder struct{ errors []string }
+
+func (r *errorRecorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}